@@ -3,17 +3,22 @@ package cli
 import (
 	"bufio"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"math/rand"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/tmaxmax/xml-to-graph/internal/graph"
@@ -21,11 +26,20 @@ import (
 )
 
 const (
-	cliName = "xml-to-graph"
+	cliName   = "xml-to-graph"
+	stdinPath = "-"
 
 	usageFlagFormat = `A C-like format string that describes how the graphs should be written.
 Your shell is responsible for handling escape sequences such as \n.
 
+The flag can be given multiple times to write multiple files for each graph, which
+is parsed only once. In this case name each format using "name=format": the name is
+used as the extension of the files written with that format. For example,
+-format "list=%n %m\n%M" -format "mat=%n\n%a" writes a "file.list" and a "file.mat"
+for each input file. A format without a name uses the extension given with the
+-output-ext flag. Start the format with "=" if it begins with text that looks like
+a name.
+
 Available verbs:
  - %: print a literal percent sign
  - n: print the number of nodes in the graph
@@ -34,6 +48,58 @@ Available verbs:
  - {cost function}w: print the cost/weight of each node
  - {cost function}N: print the nodes in the graph, optionally together with their costs
  - {cost function}M: print the edges in the graph, optionally together with their costs
+ - {cost function}L: print the adjacency list of each node on its own line, as
+   "node: neighbour1 neighbour2 ...", optionally with the cost of the edge after each
+   neighbour. Only the target of a directed edge is a neighbour of its source.
+ - {cost function}W: print the cost matrix of the graph, where each cell holds the cost
+   of the edge between the nodes, or the smallest one if there are multiple such edges
+ - I: print the incidence matrix of the graph, with a row for each node and a column
+   for each edge. Both nodes of an undirected edge are marked with 1, while the source
+   of a directed edge is marked with 1 and its target with -1.
+ - d: print the degree of each node
+ - i: print the in-degree of each node. Undirected edges are both incoming and
+   outgoing edges of their nodes.
+ - o: print the out-degree of each node
+ - {cost function}G: print the graph in the DOT language of Graphviz, as a "graph" if
+   all edges are undirected and as a "digraph" otherwise. Labels and costs are used as
+   labels, costs are also kept as "cost" attributes, and the positions from graph.jar
+   are pinned, so "neato -n" keeps the drawing.
+
+The N, M and L verbs accept the "#" flag, written right after the percent sign, which
+prints node labels instead of node IDs. Nodes without a label are printed using their
+ID, or using the text given with the -label-fallback flag. For example, "%#M" prints
+each edge as "source-label target-label". The L verb also accepts the "+" flag, which
+prints the number of neighbours instead of the "node:" prefix: "%+L" prints each line
+as "degree neighbour1 neighbour2 ...".
+
+Some verbs accept named arguments, written in square brackets right after the percent
+sign. Values which contain commas or closing brackets must be written in double quotes.
+The W verb accepts the following arguments:
+ - none: the text printed for missing edges (default "0")
+ - diag: the text printed on the diagonal for missing edges (default is none)
+For example, "%[none=INF,diag=0]W" prints "INF" for missing edges and "0" on the
+diagonal, as used by the Floyd-Warshall algorithm. The I verb accepts the following
+arguments:
+ - out: the text printed for the source of a directed edge (default "1")
+ - in: the text printed for the target of a directed edge (default "-1")
+The d, i and o verbs accept the "sort" argument, which sorts the degrees in ascending
+("asc") or descending ("desc") order. For example, "%[sort=desc]d" prints the degree
+sequence of the graph.
+
+All verbs which print more values accept the "sep" argument, the text printed between
+the values on the same row (default is a space). The N, M, L, a, W and I verbs also
+accept the "row" argument, the text printed between rows (default is a new line).
+For example, "%[sep=\",\"]M" prints the edges as "source,target", "%[sep=\"\t\"]a"
+prints a tab-separated matrix and "%[row=\" \"]M" prints all edges on one line.
+
+The N and M verbs accept the "sort" argument, which changes the order of the nodes and
+edges. By default they are printed in the order they appear in the input file:
+ - id: nodes by ID, edges by source, then by target
+ - label: nodes by label, edges by the label of the source, then of the target
+ - cost: by cost, ascending
+ - cost-desc: by cost, descending
+For example, "%[sort=id]M" prints the edges in the same order regardless of the order
+they were drawn in. See also the -normalize-undirected flag.
 
 A cost function describes how the cost of a node/edge should be printed.
 It is defined by a ratio and a rounding function. The cost function is applied
@@ -41,7 +107,7 @@ as following: the initial cost is multiplied with the ratio, then it is rounded
 using the provided function. When a verb requires a cost function,
 such as "%w", but none is provided, the default cost function is used:
 the ratio is 1 and no rounding is applied. The cost function looks like this:
- {ratio}{rounding mode}
+ {ratio}{rounding mode}{number format}
 A ratio is a floating-point number in non-scientific notation. The following are valid
 ratios:
  - .23: ratio of 0.23
@@ -53,9 +119,15 @@ cost function. The following rounding modes are allowed:
  - F: floor
  - R: round to nearest integer
  - C: ceil
+The number format tells how the cost is printed. It is optional too:
+ - none (default): the shortest representation, such as 30 or 0.30000000000000004
+ - P{decimals}: exactly this many decimals, for example P2 prints 12.5 as 12.50
+ - D: an integer, rounded to the nearest one unless a rounding mode is given
 Valid cost functions are:
  - .5: ratio 0.5, no rounding function
  - 3.6R: ratio 3.6, rounding to nearest integer
+ - P2: ratio 1, printed with two decimals
+ - 100FD: ratio 100, floored and printed as an integer
 
 Format string examples:
  - "%n %m\n%.1Fw\n%M" - Prints the number of nodes and edges, on another line the costs
@@ -68,8 +140,123 @@ Format string examples:
 
 	usageFlagOutputDir = `The directory to output the converted files to.`
 
+	usageFlagPreset = `The name of a built-in format to use. Like -format, the flag can be given multiple
+times, and the preset can be named using "name=preset". The available presets are:
+ - edges: "%n %m\n%M\n", the number of nodes and edges, then each edge
+ - weighted-edges: "%n %m\n%1M\n", the number of nodes and edges, then each edge
+   together with its cost
+ - matrix: "%n\n%a\n", the number of nodes, then the adjacency matrix
+ - adjacency-list: "%n\n%+L\n", the number of nodes, then for each node the number
+   of neighbours and the neighbours
+ - costs: "%n\n%w\n", the number of nodes, then the cost of each node`
+
+	usageFlagFormatFile = `The path to a file which contains a format string. Like -format, the flag can
+be given multiple times, and the format can be named using "name=path". The contents
+of the file are used as they are, so newlines can be written directly in the file.`
+
+	usageFlagConfig = `The path to a configuration file. If not given, a file named "xml-to-graph.conf"
+is looked for next to the executable, then in the working directory, then in the
+"xml-to-graph" directory inside the user's configuration directory, and the first
+one found is used. Each line of the file has the form "flag = value", where flag is
+the name of any other flag, without the dash, for example:
+
+	# Write an edge list and an adjacency matrix for each graph.
+	format = "list=%n %m\n%M\n"
+	format = "mat=%n\n%a\n"
+	output-dir = tests
+	id-base = 0
+
+Values in double quotes can contain escape sequences such as \n. Relative paths given
+to output-dir and format-file are relative to the directory of the configuration file.
+Flags given on the command line override the values from the configuration file.`
+
+	usageFlagPrintConfig = `Print the settings that would be used, in the configuration file format, and exit.`
+
+	usageFlagEscapes = `Interpret the escape sequences \n, \r, \t and \\ in the format strings. Use this
+when your shell does not handle them, for example with Windows' cmd.`
+
+	usageFlagIDBase = `Compact the node IDs to a dense range starting from this value, keeping their
+relative order: for example, the nodes 1, 2, 4, 5 are printed as 1, 2, 3, 4 when the
+base is 1. If not given, the node IDs are printed as they are in the input files.`
+
+	usageFlagPreserveIDs = `Print the node IDs as they are in the input files, even if -id-base is given.`
+
+	usageFlagNormalizeUndirected = `Print the endpoint with the smaller ID first for undirected edges.`
+
+	usageFlagShuffle = `Shuffle the order of the edges and randomly swap the endpoints of undirected edges
+before writing each graph, so that solutions can't rely on the order of the input.
+The shuffling is driven by the -seed flag, so the same seed gives the same files.`
+
+	usageFlagShuffleIDs = `Randomly permute the node IDs before writing each graph. Uses the -seed flag.`
+
+	usageFlagSeed = `The seed for -shuffle and -shuffle-ids. Each input file is shuffled with a seed
+derived from this one and its position in the input list, so the result doesn't depend
+on the order in which the files are converted.`
+
+	usageFlagInputFormat = `Read the input files using this format string instead of as graph.jar XML files.
+The files must have been written with the same format string, for example by an older
+conversion. Directory arguments are walked for ".in" files instead of XML, DOT and
+GraphML files.
+The -escapes, -id-base and -label-fallback flags apply to this format too.`
+
+	usageFlagInputDirected = `Read the edges of the -input-format as directed edges. By default the edges printed
+by %M are undirected, and edges which appear in both directions in an adjacency list or
+matrix are read as a single undirected edge.`
+
+	usageFlagXML = `Write each graph as a graph.jar XML file, with the "xml" extension, so that it
+can be edited in graph.jar again. This is useful together with -input-format, -shuffle
+or -shuffle-ids. If no node has a position, the nodes are placed on a circle. The XML
+file is written alongside the files of the given formats; if no format is given, only
+the XML file is written, instead of the file with the default format.`
+
+	usageFlagDOT = `Write each graph in the DOT language of Graphviz, with the "dot" extension, as
+printed by the %G verb. Render it with "neato -n" to keep the positions from graph.jar.
+Like with -xml, the file is written alongside the files of the given formats, or
+instead of the file with the default format if no format is given.`
+
+	usageFlagGraphML = `Write each graph as a GraphML file, with the "graphml" extension, which can be
+opened in yEd or Gephi. Like with -xml, the file is written alongside the files of the
+given formats, or instead of the file with the default format if no format is given.`
+
+	usageFlagCRLF = `End the lines of the converted files with CRLF ("\r\n"), as used on Windows.`
+
+	usageFlagOutputName = `A template for the names of the converted files. The following placeholders are
+replaced with their values:
+ - {name}: the name of the input file, without its extension
+ - {dir}: the name of the directory which contains the input file
+ - {ext}: the extension of the format the file is written with: the name of the
+   format, or the extension given with the -output-ext flag for unnamed formats
+ - {index}: the position of the input file in the list of files, starting from 1
+ - {nodes}: the number of nodes in the graph
+ - {edges}: the number of edges in the graph
+Numeric placeholders can be padded with zeroes by giving them a width, such as
+{index:2}. Use "{{" and "}}" for literal braces. When multiple formats are given, the
+template must contain {ext}, so that each format is written to its own file.
+
+Template examples:
+ - "grafuri{index}.{ext}": grafuri1.in, grafuri2.in, ...
+ - "{index}-{name}.{ext}": 1-test.in, 2-other.in, ...
+ - "test{index:2}.txt": test01.txt, test02.txt, ...`
+
+	usageFlagOutputExt = `The extension of the files written with an unnamed format, used by the {ext}
+placeholder of -output-name.`
+
+	usageFlagStdout = `Write the converted graphs to the standard output instead of files, in the order
+they were given. The -output-dir flag is ignored.`
+
+	usageFlagKeepGoing = `Continue converting the other files when a file fails to be converted. The files
+that failed are listed at the end, and the command exits with status 3. When disabled,
+the conversion stops at the first failure and the command exits with status 2.`
+
+	usageFlagLabelFallback = `The text printed in place of a missing node label by the verbs using the "#" flag.
+If not set, the node's ID is printed instead.`
+
 	usageFlagGlob = `A pattern that is used to match the files that will be converted. CLI arguments
-have priority over this flag.`
+have priority over this flag. The flag can be given multiple times. Besides the usual
+wildcards, "**" matches any number of directories: for example, "chapters/**/*.xml"
+matches all XML files inside the "chapters" directory and its subdirectories. The
+directory structure after the first path element with wildcards is kept in the
+output directory.`
 
 	cliDescription = `
 ██╗░░██╗███╗░░░███╗██╗░░░░░░░░░░░████████╗░█████╗░░░░░░░░██████╗░██████╗░░█████╗░██████╗░██╗░░██╗
@@ -88,29 +275,105 @@ and the converted files 'file.in' and 'another.in' will be saved by default to t
 location where xml-to-graph is called from. Customize the save location, output, and
 more using the command's flags.
 
+Graphs drawn with Graphviz, yEd or Gephi can be converted too: files with the ".dot"
+or ".gv" extension are read as DOT files, files with the ".graphml" extension as
+GraphML files, and all the other files as XML files.
+
+Directories passed as arguments are searched recursively for XML, DOT and GraphML files, and
+their directory structure is kept in the output directory.
+
+Use "-" as a path to read a graph from the standard input, and the -stdout flag to
+write the converted graphs to the standard output, for use in pipelines:
+
+	cat path/to/file.xml | xml-to-graph -stdout - > file.in
+
+To check the graphs for problems such as edges between missing nodes or duplicate
+node IDs without converting them, use the validate command:
+
+	xml-to-graph validate path/to/file.xml path/to/another.xml
+
+`
+
+	validateDescription = `Usage: xml-to-graph validate [flags] files...
+
+Checks the given graphs for problems and prints them. Errors are problems which make
+the converted files invalid, such as edges between missing nodes, duplicate node IDs
+or invalid costs. Warnings are reported for self-loops, parallel edges and graphs with
+both directed and undirected edges. The command exits with a non-zero status if any
+errors are found.
+
 `
 )
 
 type CLI struct {
-	outputDir string
-	filepaths []string
-	printer   *graph.Printer
-	ch        chan string
-	progress  chan struct{}
-	gr        *errgroup.Group
-	ctx       context.Context
-	ps        *http.Server
-	brp       sync.Pool
-	verbose   bool
+	outputDir  string
+	outputName nameTemplate
+	inputs     []input
+	outputs    []output
+	ch         chan input
+	progress   chan struct{}
+	gr         *errgroup.Group
+	ctx        context.Context
+	ps         *http.Server
+	brp        sync.Pool
+	verbose    bool
+	validate   bool
+	keepGoing  bool
+	stdout     bool
+	configPath string
+	shuffle    bool
+	shuffleIDs bool
+	seed       int64
+	// scanner reads the input files, if they aren't XML files.
+	scanner *graph.Scanner
+
+	failuresMu sync.Mutex
+	failures   []failure
+
+	outputPaths outputPaths
+}
+
+// failure records why a file could not be converted.
+type failure struct {
+	path string
+	err  error
 }
 
 func New(args []string) *CLI {
+	if len(args) > 0 && args[0] == "validate" {
+		return newValidate(args[1:])
+	}
+
 	f := flag.NewFlagSet(cliName, flag.ExitOnError)
-	formatString := f.String("format", "%n %m\n%M\n", usageFlagFormat)
+	var formats []formatSpec
+	f.Var(formatsFlag{specs: &formats, source: formatSourceString}, "format", usageFlagFormat)
+	f.Var(formatsFlag{specs: &formats, source: formatSourcePreset}, "preset", usageFlagPreset)
+	f.Var(formatsFlag{specs: &formats, source: formatSourceFile}, "format-file", usageFlagFormatFile)
+	escapes := f.Bool("escapes", false, usageFlagEscapes)
 	outputDir := f.String("output-dir", ".", usageFlagOutputDir)
-	globPattern := f.String("glob", "", usageFlagGlob)
+	var globPatterns globsFlag
+	f.Var(&globPatterns, "glob", usageFlagGlob)
 	profilerAddr := f.String("profiler", "", "The address for the pprof server (leave empty for disabling the profiler)")
 	verboseOuput := f.Bool("verbose", false, "Show various information and progress")
+	labelFallback := f.String("label-fallback", "", usageFlagLabelFallback)
+	idBase := f.Int("id-base", 0, usageFlagIDBase)
+	preserveIDs := f.Bool("preserve-ids", false, usageFlagPreserveIDs)
+	crlf := f.Bool("crlf", false, usageFlagCRLF)
+	toXML := f.Bool("xml", false, usageFlagXML)
+	toDOT := f.Bool("dot", false, usageFlagDOT)
+	toGraphML := f.Bool("graphml", false, usageFlagGraphML)
+	inputFormat := f.String("input-format", "", usageFlagInputFormat)
+	inputDirected := f.Bool("input-directed", false, usageFlagInputDirected)
+	shuffle := f.Bool("shuffle", false, usageFlagShuffle)
+	shuffleIDs := f.Bool("shuffle-ids", false, usageFlagShuffleIDs)
+	seed := f.Int64("seed", 1, usageFlagSeed)
+	normalizeUndirected := f.Bool("normalize-undirected", false, usageFlagNormalizeUndirected)
+	keepGoing := f.Bool("keep-going", true, usageFlagKeepGoing)
+	stdout := f.Bool("stdout", false, usageFlagStdout)
+	outputName := f.String("output-name", "{name}.{ext}", usageFlagOutputName)
+	outputExt := f.String("output-ext", "in", usageFlagOutputExt)
+	configPath := f.String("config", "", usageFlagConfig)
+	printConf := f.Bool("print-config", false, usageFlagPrintConfig)
 	usage := f.Usage
 	f.Usage = func() {
 		fmt.Fprint(os.Stderr, cliDescription)
@@ -118,49 +381,123 @@ func New(args []string) *CLI {
 	}
 	f.Parse(args)
 
-	fmtStr := *formatString
-	if fmtStr == "" {
-		fmtStr = f.Lookup("format").DefValue
+	if *configPath == "" {
+		*configPath, _ = findConfig()
+	}
+	if *configPath != "" {
+		if err := loadConfig(f, *configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load configuration file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *printConf {
+		printConfig(os.Stdout, f, *configPath)
+		os.Exit(0)
+	}
+
+	var opts []graph.PrinterOption
+	if *preserveIDs {
+		opts = append(opts, graph.PreserveIDs())
+	}
+	if *escapes {
+		opts = append(opts, graph.Escapes())
+	}
+	if *crlf {
+		opts = append(opts, graph.CRLF())
+	}
+	if *normalizeUndirected {
+		opts = append(opts, graph.NormalizeUndirected())
 	}
 
-	p, err := graph.ParsePrinter(fmtStr)
+	f.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "label-fallback":
+			opts = append(opts, graph.LabelFallback(*labelFallback))
+		case "id-base":
+			opts = append(opts, graph.IDBase(*idBase))
+		}
+	})
+
+	var extras []output
+	if *toXML {
+		extras = append(extras, xmlOutput)
+	}
+	if *toDOT {
+		extras = append(extras, dotOutput(opts))
+	}
+	if *toGraphML {
+		extras = append(extras, graphmlOutput)
+	}
+
+	outputs, err := parseOutputs(formats, *outputExt, extras, opts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n\n%s\n", err, usageFlagFormat)
+		var perr *graph.ParsePrinterError
+		if errors.As(err, &perr) {
+			fmt.Fprintf(os.Stderr, "%v\n\n%s\n", err, usageFlagFormat)
+		} else {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
 		os.Exit(1)
 	}
 
-	filepaths := f.Args()
-	if len(filepaths) == 0 && *globPattern != "" {
-		ps, err := filepath.Glob(*globPattern)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "glob pattern invalid: %v\n", err)
+	var scanner *graph.Scanner
+	inputExts := graphExts
+	if *inputFormat != "" {
+		var scanOpts []graph.ScannerOption
+		for _, opt := range opts {
+			scanOpts = append(scanOpts, opt)
+		}
+		if *inputDirected {
+			scanOpts = append(scanOpts, graph.DirectedEdges())
+		}
+		if scanner, err = graph.ParseScanner(*inputFormat, scanOpts...); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid input format: %v\n\n%s\n", err, usageFlagInputFormat)
 			os.Exit(1)
 		}
+		inputExts = append(inputExts[:0:0], ".in")
+	}
 
-		filepaths = ps
+	nameTmpl, err := parseNameTemplate(*outputName)
+	if err == nil && !*stdout && len(outputs) > 1 && !nameTmpl.uses(placeholderExt) {
+		err = fmt.Errorf("the %d formats would be written to the same file, add the {%s} placeholder", len(outputs), placeholderExt)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid output name template: %v\n\n%s\n", err, usageFlagOutputName)
+		os.Exit(1)
 	}
 
 	c := &CLI{
-		outputDir: *outputDir,
-		filepaths: filepaths,
-		printer:   p,
-		ch:        make(chan string),
-		progress:  make(chan struct{}),
+		outputDir:  *outputDir,
+		outputName: nameTmpl,
+		inputs:     inputs(f.Args(), globPatterns, inputExts),
+		outputs:    outputs,
+		scanner:    scanner,
+		ch:         make(chan input),
+		progress:   make(chan struct{}),
 		brp: sync.Pool{
 			New: func() interface{} {
 				return bufio.NewReader(nil)
 			},
 		},
-		verbose: *verboseOuput,
+		verbose:    *verboseOuput,
+		keepGoing:  *keepGoing,
+		stdout:     *stdout,
+		configPath: *configPath,
+		shuffle:    *shuffle,
+		shuffleIDs: *shuffleIDs,
+		seed:       *seed,
 	}
 
 	if c.outputDir == "" {
 		c.outputDir = f.Lookup("output-dir").DefValue
 	}
 
-	if err := os.MkdirAll(c.outputDir, 0600); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create output directory: %v\n", err)
-		os.Exit(2)
+	if !c.stdout {
+		if err := os.MkdirAll(c.outputDir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create output directory: %v\n", err)
+			os.Exit(2)
+		}
 	}
 
 	if *profilerAddr != "" {
@@ -172,6 +509,42 @@ func New(args []string) *CLI {
 	return c
 }
 
+func newValidate(args []string) *CLI {
+	f := flag.NewFlagSet(cliName+" validate", flag.ExitOnError)
+	var globPatterns globsFlag
+	f.Var(&globPatterns, "glob", usageFlagGlob)
+	usage := f.Usage
+	f.Usage = func() {
+		fmt.Fprint(os.Stderr, validateDescription)
+		usage()
+	}
+	f.Parse(args)
+
+	return &CLI{
+		inputs: inputs(f.Args(), globPatterns, graphExts),
+		brp: sync.Pool{
+			New: func() interface{} {
+				return bufio.NewReader(nil)
+			},
+		},
+		validate: true,
+	}
+}
+
+func inputs(args []string, globPatterns []string, exts []string) []input {
+	in, err := collectInputs(args, globPatterns, exts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to find input files: %v\n", err)
+		os.Exit(1)
+	}
+
+	for i := range in {
+		in[i].index = i + 1
+	}
+
+	return in
+}
+
 func (c *CLI) printf(format string, args ...interface{}) {
 	if c.verbose {
 		fmt.Fprintf(os.Stderr, format, args...)
@@ -179,7 +552,11 @@ func (c *CLI) printf(format string, args ...interface{}) {
 }
 
 func (c *CLI) Run() int {
-	l := len(c.filepaths)
+	if c.validate {
+		return c.runValidate()
+	}
+
+	l := len(c.inputs)
 	if l == 0 {
 		c.printf("No files to process, exiting...\n")
 		return 0
@@ -189,10 +566,18 @@ func (c *CLI) Run() int {
 	if l < workers {
 		workers = l
 	}
+	if c.stdout {
+		// The graphs are written in the order they were given.
+		workers = 1
+	}
 
 	c.printf("Starting file conversion...\nParallelism: %d workers\n", workers)
-	abs, err := filepath.Abs(c.outputDir)
-	if err == nil {
+	if c.configPath != "" {
+		c.printf("Configuration file: %s\n", c.configPath)
+	}
+	if c.stdout {
+		c.printf("Output: standard output\n")
+	} else if abs, err := filepath.Abs(c.outputDir); err == nil {
 		c.printf("Output directory: %s\n", abs)
 	}
 
@@ -255,12 +640,15 @@ func (c *CLI) Run() int {
 
 	duration := time.Now().Sub(start)
 
-	if err = <-waitErr; sctx.Err() != nil {
+	if err := <-waitErr; sctx.Err() != nil {
 		c.printf("\nConversion stopped forcefully, exiting after %v...\n", duration)
 		return 0
 	} else if err != nil {
 		fmt.Fprintf(os.Stderr, "\nFailed to process files: %v\n", err)
 		return 2
+	} else if len(c.failures) > 0 {
+		c.printFailures()
+		return 3
 	} else {
 		c.printf("\nAll files were successfully converted! Done in %v\n", duration)
 		return 0
@@ -270,9 +658,9 @@ func (c *CLI) Run() int {
 func (c *CLI) sendPaths() error {
 	defer close(c.ch)
 
-	for _, p := range c.filepaths {
+	for _, in := range c.inputs {
 		select {
-		case c.ch <- p:
+		case c.ch <- in:
 		case <-c.ctx.Done():
 			return nil
 		}
@@ -284,12 +672,25 @@ func (c *CLI) sendPaths() error {
 func (c *CLI) worker() error {
 	for {
 		select {
-		case p, ok := <-c.ch:
+		case in, ok := <-c.ch:
 			if !ok {
 				return nil
 			}
-			if err := c.processFile(p); err != nil {
-				return err
+			if err := c.processFile(in); err != nil {
+				if !c.keepGoing {
+					return fmt.Errorf("%s: %w", in.path, err)
+				}
+
+				c.failuresMu.Lock()
+				c.failures = append(c.failures, failure{path: in.path, err: err})
+				c.failuresMu.Unlock()
+			}
+
+			if c.verbose {
+				select {
+				case <-c.ctx.Done():
+				case c.progress <- struct{}{}:
+				}
 			}
 		case <-c.ctx.Done():
 			return nil
@@ -297,14 +698,29 @@ func (c *CLI) worker() error {
 	}
 }
 
+func (c *CLI) printFailures() {
+	sort.Slice(c.failures, func(i, j int) bool {
+		return c.failures[i].path < c.failures[j].path
+	})
+
+	fmt.Fprintf(os.Stderr, "\nFailed to convert %d of %d files:\n\n", len(c.failures), len(c.inputs))
+
+	tw := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tREASON")
+	for _, f := range c.failures {
+		fmt.Fprintf(tw, "%s\t%v\n", f.path, f.err)
+	}
+	tw.Flush()
+}
+
 func (c *CLI) outputProgress() error {
 	const barSize = 40
 
 	var done int
-	l := len(c.filepaths)
+	l := len(c.inputs)
 
 	printProgress := func() {
-		progress := float64(done) / float64(len(c.filepaths))
+		progress := float64(done) / float64(len(c.inputs))
 		hashes := int(float64(barSize) * progress)
 		dashes := barSize - hashes
 		barStr := strings.Repeat("#", hashes) + strings.Repeat("-", dashes)
@@ -327,40 +743,200 @@ func (c *CLI) outputProgress() error {
 	}
 }
 
-func (c *CLI) processFile(path string) error {
-	input, err := os.Open(path)
-	if err != nil {
-		return err
+func (c *CLI) runValidate() int {
+	var hasErrors, hasFailures bool
+
+	for _, in := range c.inputs {
+		p := in.path
+		g, err := c.readGraph(p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: failed to read graph: %v\n", p, err)
+			hasFailures = true
+			continue
+		}
+
+		issues := graph.Validate(&g)
+		if len(issues) == 0 {
+			fmt.Printf("%s: no issues\n", p)
+			continue
+		}
+
+		fmt.Printf("%s:\n", p)
+		for _, issue := range issues {
+			fmt.Printf("  %s\n", issue)
+			if issue.Severity == graph.Error {
+				hasErrors = true
+			}
+		}
 	}
-	defer input.Close()
 
-	outputPath := filepath.Join(c.outputDir, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))+".in")
-	output, err := os.Create(outputPath)
-	if err != nil {
-		return err
+	if hasFailures {
+		return 2
+	}
+	if hasErrors {
+		return 1
+	}
+	return 0
+}
+
+// readGraph reads the graph from the file at the given path,
+// or from the standard input if the path is "-".
+func (c *CLI) readGraph(path string) (graph.Graph, error) {
+	var input io.Reader = os.Stdin
+	if path != stdinPath {
+		f, err := os.Open(path)
+		if err != nil {
+			return graph.Graph{}, err
+		}
+		defer f.Close()
+
+		input = f
 	}
-	defer output.Close()
 
 	br := c.brp.Get().(*bufio.Reader)
 	defer c.brp.Put(br)
 	br.Reset(input)
 
-	g, err := graph.FromXMLNoStd(br)
+	return c.reader(path).ReadGraph(br)
+}
+
+// reader returns the reader for the file at the given path, chosen by its extension.
+func (c *CLI) reader(path string) graph.Reader {
+	switch {
+	case hasExt(path, dotExts):
+		return graph.DOTReader
+	case hasExt(path, graphmlExts):
+		return graph.GraphMLReader
+	case c.scanner != nil:
+		return c.scanner
+	default:
+		return graph.XMLReader
+	}
+}
+
+func (c *CLI) processFile(in input) error {
+	g, err := c.readGraph(in.path)
 	if err != nil {
 		return err
 	}
 
-	_, err = c.printer.Print(output, &g)
+	if c.shuffle || c.shuffleIDs {
+		r := rand.New(rand.NewSource(c.seed + int64(in.index)))
+		if c.shuffleIDs {
+			graph.PermuteIDs(&g, r)
+		}
+		if c.shuffle {
+			graph.Shuffle(&g, r)
+		}
+	}
+
+	if c.stdout {
+		for _, o := range c.outputs {
+			if err = o.print(os.Stdout, &g); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	data := nameData{
+		name:  "stdin",
+		index: in.index,
+		nodes: len(g.Nodes),
+		edges: len(g.Edges),
+	}
+	if in.path != stdinPath {
+		data.name = strings.TrimSuffix(filepath.Base(in.path), filepath.Ext(in.path))
+		dir, err := filepath.Abs(filepath.Dir(in.path))
+		if err != nil {
+			dir = filepath.Dir(in.path)
+		}
+		data.dir = filepath.Base(dir)
+	}
+
+	paths, err := c.outputFiles(in, data)
 	if err != nil {
 		return err
 	}
 
-	if c.verbose {
-		select {
-		case <-c.ctx.Done():
-		case c.progress <- struct{}{}:
+	for i, o := range c.outputs {
+		if err := os.MkdirAll(filepath.Dir(paths[i]), 0755); err != nil {
+			return err
+		}
+
+		err := c.writeAtomic(paths[i], func(w io.Writer) error {
+			return o.print(w, &g)
+		})
+		if err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
+
+// outputFiles returns the path of the file written for each output, checking
+// before anything is written that no two of them are the same and that no other
+// input is converted to any of them.
+func (c *CLI) outputFiles(in input, data nameData) ([]string, error) {
+	paths := make([]string, len(c.outputs))
+
+	for i, o := range c.outputs {
+		data.ext = o.ext
+		p := filepath.Join(c.outputDir, in.dir, c.outputName.execute(data))
+		if samePath(p, in.path) {
+			return nil, fmt.Errorf("output file %s would overwrite the input file", p)
+		}
+		for j := range paths[:i] {
+			if paths[j] == p {
+				return nil, fmt.Errorf("formats %q and %q would both be written to %s", c.outputs[j].ext, o.ext, p)
+			}
+		}
+		paths[i] = p
+	}
+
+	for _, p := range paths {
+		if err := c.outputPaths.claim(p, in.path); err != nil {
+			return nil, err
+		}
+	}
+
+	return paths, nil
+}
+
+// writeAtomic writes the output to a temporary file in the same directory
+// as the given path, and moves it in place only after write succeeds. This way
+// no truncated files are left behind when writing fails or the conversion
+// is stopped.
+func (c *CLI) writeAtomic(path string, write func(io.Writer) error) (err error) {
+	output, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+
+	closed := false
+	defer func() {
+		if err != nil {
+			if !closed {
+				output.Close()
+			}
+			os.Remove(output.Name())
+		}
+	}()
+
+	if err = write(output); err != nil {
+		return err
+	}
+	if err = c.ctx.Err(); err != nil {
+		return err
+	}
+	if err = output.Chmod(0644); err != nil {
+		return err
+	}
+	closed = true
+	if err = output.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(output.Name(), path)
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newTestCLI creates the command with the given arguments and an empty
+// configuration file, so that the configuration files of the user aren't used.
+func newTestCLI(t *testing.T, args ...string) *CLI {
+	config := filepath.Join(t.TempDir(), configFileName)
+	if err := os.WriteFile(config, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return New(append([]string{"-config", config}, args...))
+}
+
+func TestRunFailures(t *testing.T) {
+	dir, outputDir := t.TempDir(), t.TempDir()
+
+	files := map[string]string{
+		"good.xml":      "<graph>\n <node id=\"1\"></node>\n</graph>\n",
+		"garbage.xml":   "garbage",
+		"truncated.xml": "<graph>\n <node id=\"1\"></node>\n",
+	}
+	var args []string
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		args = append(args, p)
+	}
+
+	c := newTestCLI(t, append([]string{"-output-dir", outputDir}, args...)...)
+	if code := c.Run(); code != 3 {
+		t.Fatalf("Expected exit status 3, received %d", code)
+	}
+	if len(c.failures) != 2 {
+		t.Fatalf("Expected 2 failures, received %+v", c.failures)
+	}
+
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "good.in" {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Fatalf("Expected only good.in to be written, found %q", names)
+	}
+}
+
+func TestRunOutputClash(t *testing.T) {
+	dir, outputDir := t.TempDir(), t.TempDir()
+
+	var args []string
+	for _, name := range []string{"a", "b"} {
+		p := filepath.Join(dir, name, "graph.xml")
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("<graph>\n</graph>\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		args = append(args, filepath.Dir(p))
+	}
+
+	c := newTestCLI(t, append([]string{"-output-dir", outputDir}, args...)...)
+	if code := c.Run(); code != 3 {
+		t.Fatalf("Expected exit status 3, received %d", code)
+	}
+	if len(c.failures) != 1 {
+		t.Fatalf("Expected 1 failure, received %+v", c.failures)
+	}
+	t.Log(c.failures[0].err)
+}
+
+func TestOutputFiles(t *testing.T) {
+	outputs, err := parseOutputs([]formatSpec{{name: "a", format: "%n\n"}, {name: "b", format: "%m\n"}}, "in", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		template string
+		paths    []string
+	}{
+		{template: "{name}.{ext}", paths: []string{"out/sub/graf.a", "out/sub/graf.b"}},
+		{template: "test{index:2}.txt"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.template, func(t *testing.T) {
+			tmpl, err := parseNameTemplate(test.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := &CLI{outputDir: "out", outputName: tmpl, outputs: outputs}
+			paths, err := c.outputFiles(input{path: "sub/graf.xml", dir: "sub", index: 1}, nameData{name: "graf", index: 1})
+			if test.paths == nil {
+				if err == nil {
+					t.Fatalf("Expected error, received paths %q", paths)
+				}
+				t.Log(err)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := range paths {
+				paths[i] = filepath.ToSlash(paths[i])
+			}
+			if !reflect.DeepEqual(paths, test.paths) {
+				t.Fatalf("Invalid paths:\nexpected %q\nreceived %q", test.paths, paths)
+			}
+		})
+	}
+
+	c := &CLI{outputDir: "out", outputName: nameTemplate{{placeholder: placeholderName}}, outputs: outputs[:1]}
+	if _, err := c.outputFiles(input{path: "a/graf.xml"}, nameData{name: "graf"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.outputFiles(input{path: "b/graf.xml"}, nameData{name: "graf"}); err == nil {
+		t.Fatal("Expected error for an output file written for two inputs")
+	}
+}
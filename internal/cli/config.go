@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const configFileName = cliName + ".conf"
+
+// configIgnoredFlags are the flags which can't be set from a configuration file.
+var configIgnoredFlags = map[string]bool{
+	"config":       true,
+	"print-config": true,
+}
+
+// configFlagGroups lists the flags which share their values. If any flag of a group
+// is given on the command line, the configuration file values of the entire group
+// are ignored.
+var configFlagGroups = [][]string{
+	{"format", "preset", "format-file"},
+}
+
+// multiValue is implemented by the flags which can be given multiple times.
+type multiValue interface {
+	// values returns the values to be written to a configuration file
+	// for the flag, in the order they were given.
+	values() []string
+}
+
+// configPathFlags are the flags whose values are paths. Relative paths in a
+// configuration file are resolved against its directory, as the working directory
+// is arbitrary when the command is started by drag-and-drop.
+var configPathFlags = map[string]bool{
+	"output-dir":  true,
+	"format-file": true,
+}
+
+// findConfig returns the path of the configuration file to be used. It is looked
+// for next to the executable, then in the working directory, then in the user's
+// configuration directory. The first file found is used.
+func findConfig() (string, bool) {
+	var dirs []string
+	if exe, err := os.Executable(); err == nil {
+		dirs = append(dirs, filepath.Dir(exe))
+	}
+	if wd, err := os.Getwd(); err == nil {
+		dirs = append(dirs, wd)
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(dir, cliName))
+	}
+
+	for _, dir := range dirs {
+		p := filepath.Join(dir, configFileName)
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p, true
+		}
+	}
+
+	return "", false
+}
+
+// loadConfig sets the flags from the configuration file at the given path.
+// Flags which were given on the command line are not changed.
+//
+// Each line of the configuration file has the form "flag = value", where flag
+// is the name of a command line flag without the leading dash. The value can be
+// written as a Go string literal, in double quotes, to use escape sequences such
+// as \n. Flags that can be given multiple times can appear on multiple lines.
+// Empty lines and lines starting with "#" are ignored.
+func loadConfig(f *flag.FlagSet, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fromArgs := map[string]bool{}
+	f.Visit(func(fl *flag.Flag) {
+		fromArgs[fl.Name] = true
+	})
+	for _, group := range configFlagGroups {
+		var set bool
+		for _, name := range group {
+			set = set || fromArgs[name]
+		}
+		for _, name := range group {
+			fromArgs[name] = set
+		}
+	}
+
+	sc := bufio.NewScanner(file)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+
+		name, value, err := parseConfigLine(text)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if f.Lookup(name) == nil || configIgnoredFlags[name] {
+			return fmt.Errorf("%s:%d: unknown setting %q", path, line, name)
+		}
+		if fromArgs[name] {
+			continue
+		}
+		if configPathFlags[name] {
+			value = resolveConfigPath(name, value, filepath.Dir(path))
+		}
+		if err := f.Set(name, value); err != nil {
+			return fmt.Errorf("%s:%d: invalid value for %q: %w", path, line, name, err)
+		}
+	}
+
+	return sc.Err()
+}
+
+// resolveConfigPath returns the value of the flag with its path made relative
+// to dir. The path given to -format-file can be preceded by a format name.
+func resolveConfigPath(name, value, dir string) string {
+	var prefix string
+	if name == "format-file" {
+		var formatName string
+		formatName, value = splitFormat(value)
+		prefix = formatName + "="
+	}
+	if value == "" || filepath.IsAbs(value) {
+		return prefix + value
+	}
+
+	return prefix + filepath.Join(dir, value)
+}
+
+func parseConfigLine(text string) (name, value string, err error) {
+	i := strings.IndexByte(text, '=')
+	if i == -1 {
+		return "", "", fmt.Errorf("expected \"setting = value\", got %q", text)
+	}
+
+	name = strings.TrimSpace(text[:i])
+	value = strings.TrimSpace(text[i+1:])
+
+	if strings.HasPrefix(value, `"`) {
+		if value, err = strconv.Unquote(value); err != nil {
+			return "", "", fmt.Errorf("invalid quoted value: %w", err)
+		}
+	}
+
+	return name, value, nil
+}
+
+// printConfig writes the values of all flags in the configuration file format.
+func printConfig(w io.Writer, f *flag.FlagSet, path string) {
+	if path != "" {
+		fmt.Fprintf(w, "# Loaded from %s\n", path)
+	} else {
+		fmt.Fprintf(w, "# No configuration file found, save this as %s to create one\n", configFileName)
+	}
+
+	set := map[string]bool{}
+	f.Visit(func(fl *flag.Flag) {
+		set[fl.Name] = true
+	})
+
+	f.VisitAll(func(fl *flag.Flag) {
+		if configIgnoredFlags[fl.Name] {
+			return
+		}
+
+		if mv, ok := fl.Value.(multiValue); ok {
+			for _, v := range mv.values() {
+				fmt.Fprintf(w, "%s = %s\n", fl.Name, quoteConfigValue(v))
+			}
+			return
+		}
+
+		v := fl.Value.String()
+		if !set[fl.Name] && (v == "" || fl.Name == "id-base") {
+			// Setting a flag may not be the same as not setting it at all, even
+			// to its default value: for example an empty -label-fallback replaces
+			// the missing labels, and -id-base enables the compaction of IDs.
+			return
+		}
+
+		fmt.Fprintf(w, "%s = %s\n", fl.Name, quoteConfigValue(v))
+	})
+}
+
+func quoteConfigValue(v string) string {
+	if v == "" || strings.TrimSpace(v) != v || strings.HasPrefix(v, `"`) || strconv.Quote(v) != `"`+v+`"` {
+		return strconv.Quote(v)
+	}
+	return v
+}
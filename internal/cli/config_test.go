@@ -0,0 +1,256 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tmaxmax/xml-to-graph/internal/graph"
+)
+
+func TestParseConfigLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		name  string
+		value string
+	}{
+		{line: "format = %n %m", name: "format", value: "%n %m"},
+		{line: "format=%n", name: "format", value: "%n"},
+		{line: `format = "%n %m\n%M\n"`, name: "format", value: "%n %m\n%M\n"},
+		{line: `label-fallback = ""`, name: "label-fallback", value: ""},
+		{line: "format = edges=%M", name: "format", value: "edges=%M"},
+		{line: "  output-ext   =   txt  ", name: "output-ext", value: "txt"},
+		{line: `output-ext = a"b"`, name: "output-ext", value: `a"b"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			name, value, err := parseConfigLine(strings.TrimSpace(test.line))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name != test.name || value != test.value {
+				t.Fatalf("Invalid setting:\nexpected %q = %q\nreceived %q = %q", test.name, test.value, name, value)
+			}
+		})
+	}
+
+	for _, line := range []string{"format", `format = "%n`, `format = "\q"`} {
+		if _, _, err := parseConfigLine(line); err == nil {
+			t.Fatalf("Expected error for line %q", line)
+		}
+	}
+}
+
+func writeConfig(t *testing.T, dir, content string) string {
+	p := filepath.Join(dir, configFileName)
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+const testConfig = `# Converted files for the first chapter
+format = %n\t%m
+
+escapes = true
+  # Both patterns are used
+glob = *.xml
+glob = sub/*.xml
+output-ext = txt
+`
+
+func TestLoadConfig(t *testing.T) {
+	chdirTestTree(t)
+	outputDir := t.TempDir()
+	path := writeConfig(t, t.TempDir(), testConfig+"output-dir = "+outputDir+"\n")
+
+	tests := []struct {
+		name   string
+		args   []string
+		exts   []string
+		output string
+		inputs []string
+	}{
+		{
+			name:   "no arguments",
+			exts:   []string{"txt"},
+			output: "0\t0",
+			inputs: []string{"a.xml", "sub/b.xml"},
+		},
+		{
+			name:   "arguments override",
+			args:   []string{"-output-ext", "out", "-glob", "sub/*.xml", "-escapes=false"},
+			exts:   []string{"out"},
+			output: `0\t0`,
+			inputs: []string{"sub/b.xml"},
+		},
+		{
+			name:   "format flag group",
+			args:   []string{"-preset", "m=costs"},
+			exts:   []string{"m"},
+			output: "0\n\n",
+			inputs: []string{"a.xml", "sub/b.xml"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := New(append([]string{"-config", path}, test.args...))
+
+			if c.outputDir != outputDir || c.configPath != path {
+				t.Fatalf("Invalid paths: output directory %q, configuration file %q", c.outputDir, c.configPath)
+			}
+
+			var exts []string
+			for _, o := range c.outputs {
+				exts = append(exts, o.ext)
+			}
+			if !reflect.DeepEqual(exts, test.exts) {
+				t.Fatalf("Invalid outputs:\nexpected %q\nreceived %q", test.exts, exts)
+			}
+
+			var buf bytes.Buffer
+			if _, err := c.outputs[0].printer.Print(&buf, &graph.Graph{}); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != test.output {
+				t.Fatalf("Invalid output:\nexpected %q\nreceived %q", test.output, buf.String())
+			}
+
+			var inputs []string
+			for _, in := range c.inputs {
+				inputs = append(inputs, filepath.ToSlash(in.path))
+			}
+			if !reflect.DeepEqual(inputs, test.inputs) {
+				t.Fatalf("Invalid inputs:\nexpected %q\nreceived %q", test.inputs, inputs)
+			}
+		})
+	}
+}
+
+func TestLoadConfigRelativePaths(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, "output-dir = out\nformat-file = list=list.txt\n")
+	if err := os.WriteFile(filepath.Join(dir, "list.txt"), []byte("%n %m"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New([]string{"-config", path})
+	if expected := filepath.Join(dir, "out"); c.outputDir != expected {
+		t.Fatalf("Invalid output directory:\nexpected %q\nreceived %q", expected, c.outputDir)
+	}
+	if len(c.outputs) != 1 || c.outputs[0].ext != "list" || c.outputs[0].format != "%n %m" {
+		t.Fatalf("Invalid outputs: %+v", c.outputs)
+	}
+}
+
+func TestResolveConfigPath(t *testing.T) {
+	dir := t.TempDir()
+	abs := filepath.Join(dir, "abs")
+
+	tests := []struct {
+		name, value, expected string
+	}{
+		{name: "output-dir", value: "tests", expected: filepath.Join(dir, "tests")},
+		{name: "output-dir", value: abs, expected: abs},
+		{name: "output-dir", value: "", expected: ""},
+		{name: "format-file", value: "list=f.txt", expected: "list=" + filepath.Join(dir, "f.txt")},
+		{name: "format-file", value: "f.txt", expected: "=" + filepath.Join(dir, "f.txt")},
+		{name: "format-file", value: "=a=b.txt", expected: "=" + filepath.Join(dir, "a=b.txt")},
+		{name: "format-file", value: "mat=" + abs, expected: "mat=" + abs},
+	}
+
+	for _, test := range tests {
+		if v := resolveConfigPath(test.name, test.value, dir); v != test.expected {
+			t.Fatalf("Invalid %s value for %q:\nexpected %q\nreceived %q", test.name, test.value, test.expected, v)
+		}
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	newFlags := func() *flag.FlagSet {
+		f := flag.NewFlagSet(cliName, flag.ContinueOnError)
+		f.String("format", "", "")
+		f.Bool("escapes", false, "")
+		f.String("config", "", "")
+		return f
+	}
+
+	for _, content := range []string{
+		"formats = %n\n",
+		"config = other.conf\n",
+		"escapes = maybe\n",
+		"# no value\nformat\n",
+		"format = \"%n\n",
+	} {
+		t.Run(content, func(t *testing.T) {
+			err := loadConfig(newFlags(), writeConfig(t, dir, content))
+			if err == nil {
+				t.Fatalf("Expected error for configuration %q", content)
+			}
+			t.Log(err)
+		})
+	}
+
+	if err := loadConfig(newFlags(), filepath.Join(dir, "missing.conf")); err == nil {
+		t.Fatalf("Expected error for a missing configuration file")
+	}
+}
+
+func TestFindConfig(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cwdDir, exeDir, userDir := t.TempDir(), filepath.Dir(exe), t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", userDir)
+	t.Setenv("HOME", userDir)
+	if err := os.Chdir(cwdDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if p, ok := findConfig(); ok {
+		t.Fatalf("Expected no configuration file, found %q", p)
+	}
+
+	if err := os.MkdirAll(filepath.Join(userDir, cliName), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Each file takes precedence over the ones written before it.
+	for _, dir := range []string{filepath.Join(userDir, cliName), cwdDir, exeDir} {
+		expected := writeConfig(t, dir, "")
+		if dir == exeDir {
+			t.Cleanup(func() { os.Remove(expected) })
+		}
+
+		p, ok := findConfig()
+		if !ok || !sameFile(t, p, expected) {
+			t.Fatalf("Expected configuration file %q, found %q", expected, p)
+		}
+	}
+}
+
+func sameFile(t *testing.T, a, b string) bool {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return os.SameFile(infoA, infoB)
+}
@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tmaxmax/xml-to-graph/internal/graph"
+)
+
+// defaultFormat is the format used when no format is given.
+const defaultFormat = "%n %m\n%M\n"
+
+// output is a file written for every input, using its own printer.
+type output struct {
+	// ext is the extension of the file, used by the {ext} placeholder.
+	ext    string
+	format string
+	// printer is nil for the outputs which aren't written using a format string.
+	printer *graph.Printer
+	// write writes the outputs without a printer.
+	write func(io.Writer, *graph.Graph) error
+}
+
+// xmlOutput writes the graph.jar XML files for the -xml flag.
+var xmlOutput = output{ext: "xml", write: graph.ToXML}
+
+// dotFormat is the format used to write the Graphviz files for the -dot flag.
+const dotFormat = "%G\n"
+
+// dotOutput creates the output which writes the Graphviz files for the -dot flag.
+func dotOutput(opts []graph.PrinterOption) output {
+	return output{ext: "dot", format: dotFormat, printer: graph.MustParsePrinter(dotFormat, opts...)}
+}
+
+// graphmlOutput writes the GraphML files for the -graphml flag.
+var graphmlOutput = output{ext: "graphml", write: graph.ToGraphML}
+
+func (o *output) print(w io.Writer, g *graph.Graph) error {
+	if o.printer == nil {
+		return o.write(w, g)
+	}
+
+	_, err := o.printer.Print(w, g)
+	return err
+}
+
+// formatSpec is a format string given on the command line, with an optional name.
+type formatSpec struct {
+	name   string
+	format string
+}
+
+// formatSource tells how the value of a formatsFlag is turned into a format string.
+type formatSource int
+
+const (
+	// The value is the format string.
+	formatSourceString formatSource = iota
+	// The value is the name of a preset.
+	formatSourcePreset
+	// The value is the path to a file which contains the format string.
+	formatSourceFile
+)
+
+// formatsFlag is a flag that can be given multiple times. The -format, -preset
+// and -format-file flags share the same list of formats, so the formats are kept
+// in the order they were given, regardless of the flag used.
+type formatsFlag struct {
+	specs  *[]formatSpec
+	source formatSource
+}
+
+func (f formatsFlag) String() string {
+	if f.specs == nil {
+		return ""
+	}
+
+	var parts []string
+	for _, s := range *f.specs {
+		parts = append(parts, s.name+"="+s.format)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f formatsFlag) Set(v string) error {
+	name, value := splitFormat(v)
+	spec := formatSpec{name: name, format: value}
+
+	switch f.source {
+	case formatSourcePreset:
+		format, ok := graph.Preset(value)
+		if !ok {
+			return fmt.Errorf("unknown preset %q, available presets are: %s", value, strings.Join(graph.PresetNames(), ", "))
+		}
+		spec.format = format
+	case formatSourceFile:
+		data, err := os.ReadFile(value)
+		if err != nil {
+			return err
+		}
+		spec.format = string(data)
+	}
+
+	*f.specs = append(*f.specs, spec)
+	return nil
+}
+
+// values returns all the formats for the -format flag, with the presets and the
+// contents of the format files included, and nothing for the other flags.
+func (f formatsFlag) values() []string {
+	if f.source != formatSourceString {
+		return nil
+	}
+	if len(*f.specs) == 0 {
+		return []string{defaultFormat}
+	}
+
+	vs := make([]string, 0, len(*f.specs))
+	for _, s := range *f.specs {
+		if s.name != "" {
+			vs = append(vs, s.name+"="+s.format)
+		} else if name, _ := splitFormat(s.format); name != "" {
+			vs = append(vs, "="+s.format)
+		} else {
+			vs = append(vs, s.format)
+		}
+	}
+
+	return vs
+}
+
+// splitFormat separates the name of a format from the format string.
+// A name is a prefix made of letters, digits, dots, dashes or underscores,
+// followed by "=". A format string starting with "=" has no name, the "="
+// being removed.
+func splitFormat(v string) (name, format string) {
+	i := strings.IndexFunc(v, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_')
+	})
+	if i == -1 || v[i] != '=' {
+		return "", v
+	}
+
+	return v[:i], v[i+1:]
+}
+
+// parseOutputs creates an output for each format, followed by the extra outputs,
+// such as the ones of the -xml, -dot and -graphml flags. Unnamed formats use the
+// default extension. The default format is used only if there are neither formats
+// nor extra outputs. The extensions of the outputs must be unique.
+func parseOutputs(formats []formatSpec, defaultExt string, extras []output, opts []graph.PrinterOption) ([]output, error) {
+	if len(formats) == 0 && len(extras) == 0 {
+		formats = []formatSpec{{format: defaultFormat}}
+	}
+
+	outputs := make([]output, 0, len(formats)+len(extras))
+	seen := make(map[string]bool, len(formats)+len(extras))
+
+	// The extra outputs are written last, but their extensions are reserved.
+	for _, o := range extras {
+		seen[o.ext] = true
+	}
+
+	for _, f := range formats {
+		name, format := f.name, f.format
+		if name == "" {
+			name = defaultExt
+		}
+		if format == "" {
+			format = defaultFormat
+		}
+
+		if seen[name] {
+			return nil, fmt.Errorf("format %q is given more than once, name the formats using \"name=format\"", name)
+		}
+		seen[name] = true
+
+		p, err := graph.ParsePrinter(format, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("format %q: %w", name, err)
+		}
+
+		outputs = append(outputs, output{ext: name, format: format, printer: p})
+	}
+
+	return append(outputs, extras...), nil
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/tmaxmax/xml-to-graph/internal/graph"
+)
+
+func TestSplitFormat(t *testing.T) {
+	tests := []struct {
+		value  string
+		name   string
+		format string
+	}{
+		{value: "%n %m\n%M\n", format: "%n %m\n%M\n"},
+		{value: "in=%n\n", name: "in", format: "%n\n"},
+		{value: "test.out-1_a=%M", name: "test.out-1_a", format: "%M"},
+		{value: "=a=%n", format: "a=%n"},
+		{value: "Nodes = %n", format: "Nodes = %n"},
+		{value: "a,b=%n", format: "a,b=%n"},
+		{value: "in=", name: "in"},
+		{value: "", format: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			name, format := splitFormat(test.value)
+			if name != test.name || format != test.format {
+				t.Fatalf("Invalid split:\nexpected %q, %q\nreceived %q, %q", test.name, test.format, name, format)
+			}
+		})
+	}
+}
+
+func TestParseOutputs(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []formatSpec
+		extras  []output
+		exts    []string
+		formats []string
+	}{
+		{name: "default", exts: []string{"in"}, formats: []string{defaultFormat}},
+		{name: "unnamed", specs: []formatSpec{{format: "%n\n"}}, exts: []string{"in"}, formats: []string{"%n\n"}},
+		{name: "empty format", specs: []formatSpec{{name: "a"}}, exts: []string{"a"}, formats: []string{defaultFormat}},
+		{
+			name:    "named",
+			specs:   []formatSpec{{name: "a", format: "%n\n"}, {format: "%m\n"}, {name: "xml", format: "%M\n"}},
+			exts:    []string{"a", "in", "xml"},
+			formats: []string{"%n\n", "%m\n", "%M\n"},
+		},
+		{name: "extra output only", extras: []output{xmlOutput}, exts: []string{"xml"}, formats: []string{""}},
+		{
+			name:    "extra output",
+			specs:   []formatSpec{{format: "%n\n"}},
+			extras:  []output{xmlOutput},
+			exts:    []string{"in", "xml"},
+			formats: []string{"%n\n", ""},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			outputs, err := parseOutputs(test.specs, "in", test.extras, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(outputs) != len(test.exts) {
+				t.Fatalf("Expected %d outputs, received %+v", len(test.exts), outputs)
+			}
+
+			for i, o := range outputs {
+				if o.ext != test.exts[i] || o.format != test.formats[i] {
+					t.Fatalf("Invalid output %d:\nexpected %q, %q\nreceived %q, %q", i, test.exts[i], test.formats[i], o.ext, o.format)
+				}
+				if (o.printer == nil) != (i >= len(outputs)-len(test.extras)) {
+					t.Fatalf("Output %q must have a printer only if it has a format", o.ext)
+				}
+			}
+		})
+	}
+}
+
+func TestParseOutputsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		formats []formatSpec
+		extras  []output
+	}{
+		{name: "duplicate default extension", formats: []formatSpec{{format: "%n\n"}, {format: "%m\n"}}},
+		{name: "duplicate extension", formats: []formatSpec{{name: "a", format: "%n\n"}, {name: "a", format: "%m\n"}}},
+		{name: "named default extension", formats: []formatSpec{{format: "%n\n"}, {name: "in", format: "%m\n"}}},
+		{name: "invalid format", formats: []formatSpec{{format: "%Q"}}},
+		{name: "extra output extension", formats: []formatSpec{{name: "xml", format: "%n\n"}}, extras: []output{xmlOutput}},
+		{name: "dot extension", formats: []formatSpec{{name: "dot", format: "%n\n"}}, extras: []output{xmlOutput, dotOutput(nil)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseOutputs(test.formats, "in", test.extras, []graph.PrinterOption{graph.Escapes()})
+			if err == nil {
+				t.Fatalf("Expected error for formats %+v", test.formats)
+			}
+			t.Log(err)
+		})
+	}
+}
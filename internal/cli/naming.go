@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	placeholderName  = "name"
+	placeholderDir   = "dir"
+	placeholderIndex = "index"
+	placeholderNodes = "nodes"
+	placeholderEdges = "edges"
+	placeholderExt   = "ext"
+)
+
+// nameTemplate builds the names of the output files.
+type nameTemplate []namePart
+
+// namePart is either literal text or a placeholder,
+// optionally padded with zeroes to the given width.
+type namePart struct {
+	text        string
+	placeholder string
+	width       int
+}
+
+// nameData holds the values of the placeholders for an output file.
+type nameData struct {
+	name  string
+	dir   string
+	ext   string
+	index int
+	nodes int
+	edges int
+}
+
+func parseNameTemplate(s string) (nameTemplate, error) {
+	var t nameTemplate
+	var text strings.Builder
+
+	for s != "" {
+		i := strings.IndexAny(s, "{}")
+		if i == -1 {
+			text.WriteString(s)
+			break
+		}
+
+		text.WriteString(s[:i])
+		if i+1 < len(s) && s[i+1] == s[i] {
+			text.WriteByte(s[i])
+			s = s[i+2:]
+			continue
+		}
+		if s[i] == '}' {
+			return nil, errors.New(`unexpected "}", use "}}" for a literal brace`)
+		}
+
+		end := strings.IndexByte(s[i:], '}')
+		if end == -1 {
+			return nil, errors.New(`unterminated placeholder, use "{{" for a literal brace`)
+		}
+
+		part, err := parsePlaceholder(s[i+1 : i+end])
+		if err != nil {
+			return nil, err
+		}
+
+		if text.Len() > 0 {
+			t = append(t, namePart{text: text.String()})
+			text.Reset()
+		}
+		t = append(t, part)
+		s = s[i+end+1:]
+	}
+
+	if text.Len() > 0 {
+		t = append(t, namePart{text: text.String()})
+	}
+	if len(t) == 0 {
+		return nil, errors.New("template is empty")
+	}
+
+	return t, nil
+}
+
+func parsePlaceholder(s string) (namePart, error) {
+	p := namePart{placeholder: s}
+
+	if i := strings.IndexByte(s, ':'); i != -1 {
+		p.placeholder = s[:i]
+
+		var err error
+		p.width, err = strconv.Atoi(s[i+1:])
+		if err != nil || p.width < 0 {
+			return namePart{}, fmt.Errorf("invalid width for placeholder %q", p.placeholder)
+		}
+	}
+
+	switch p.placeholder {
+	case placeholderName, placeholderDir, placeholderExt:
+		if p.width != 0 {
+			return namePart{}, fmt.Errorf("placeholder %q does not accept a width", p.placeholder)
+		}
+	case placeholderIndex, placeholderNodes, placeholderEdges:
+	default:
+		return namePart{}, fmt.Errorf("unknown placeholder %q", p.placeholder)
+	}
+
+	return p, nil
+}
+
+func (t nameTemplate) execute(d nameData) string {
+	var sb strings.Builder
+
+	for _, p := range t {
+		switch p.placeholder {
+		case "":
+			sb.WriteString(p.text)
+		case placeholderName:
+			sb.WriteString(d.name)
+		case placeholderDir:
+			sb.WriteString(d.dir)
+		case placeholderExt:
+			sb.WriteString(d.ext)
+		case placeholderIndex:
+			writePadded(&sb, d.index, p.width)
+		case placeholderNodes:
+			writePadded(&sb, d.nodes, p.width)
+		case placeholderEdges:
+			writePadded(&sb, d.edges, p.width)
+		}
+	}
+
+	return sb.String()
+}
+
+// uses reports whether the template contains the given placeholder.
+func (t nameTemplate) uses(placeholder string) bool {
+	for _, p := range t {
+		if p.placeholder == placeholder {
+			return true
+		}
+	}
+	return false
+}
+
+func writePadded(sb *strings.Builder, v, width int) {
+	s := strconv.Itoa(v)
+	for i := len(s); i < width; i++ {
+		sb.WriteByte('0')
+	}
+	sb.WriteString(s)
+}
@@ -0,0 +1,74 @@
+package cli
+
+import "testing"
+
+func TestNameTemplate(t *testing.T) {
+	data := nameData{name: "graf", dir: "cap1", ext: "in", index: 7, nodes: 12, edges: 345}
+
+	tests := []struct {
+		template string
+		output   string
+	}{
+		{template: "{name}.{ext}", output: "graf.in"},
+		{template: "{dir}-{name}", output: "cap1-graf"},
+		{template: "grafuri{index}.{ext}", output: "grafuri7.in"},
+		{template: "test{index:2}.txt", output: "test07.txt"},
+		{template: "{index:0}", output: "7"},
+		{template: "{nodes}_{edges}", output: "12_345"},
+		{template: "{nodes:3}_{edges:2}", output: "012_345"},
+		{template: "{{name}}", output: "{name}"},
+		{template: "a}}b{{{ext}", output: "a}b{in"},
+		{template: "static.in", output: "static.in"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.template, func(t *testing.T) {
+			tmpl, err := parseNameTemplate(test.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if output := tmpl.execute(data); output != test.output {
+				t.Fatalf("Invalid output:\nexpected %q\nreceived %q", test.output, output)
+			}
+		})
+	}
+}
+
+func TestNameTemplateErrors(t *testing.T) {
+	for _, template := range []string{
+		"",
+		"{size}",
+		"{}",
+		"{name",
+		"name}",
+		"{index:-1}",
+		"{index:x}",
+		"{nodes:}",
+		"{name:2}",
+		"{dir:1}",
+		"{ext:3}",
+	} {
+		t.Run(template, func(t *testing.T) {
+			_, err := parseNameTemplate(template)
+			if err == nil {
+				t.Fatalf("Expected error for template %q", template)
+			}
+			t.Log(err)
+		})
+	}
+}
+
+func TestNameTemplateUses(t *testing.T) {
+	tmpl, err := parseNameTemplate("{name}-{index:2}.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !tmpl.uses(placeholderName) || !tmpl.uses(placeholderIndex) {
+		t.Fatalf("Template %+v should use {name} and {index}", tmpl)
+	}
+	if tmpl.uses(placeholderExt) {
+		t.Fatalf("Template %+v shouldn't use {ext}", tmpl)
+	}
+}
@@ -0,0 +1,266 @@
+package cli
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// input is a file to be converted.
+type input struct {
+	path string
+	// dir is the directory, relative to the output directory,
+	// where the converted file is saved.
+	dir string
+	// index is the 1-based position of the file in the list of inputs.
+	index int
+}
+
+// outputPaths records for which input each output file is written, so that
+// inputs whose converted files would have the same path are reported instead
+// of overwriting each other.
+type outputPaths struct {
+	mu    sync.Mutex
+	paths map[string]string
+}
+
+// claim records that the file at path is written for the given input.
+// It fails if the file is already written for another input.
+func (o *outputPaths) claim(path, input string) error {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		key = filepath.Clean(path)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if other, ok := o.paths[key]; ok && other != input {
+		return fmt.Errorf("output file %s is also written for %s", path, other)
+	}
+	if o.paths == nil {
+		o.paths = map[string]string{}
+	}
+	o.paths[key] = input
+
+	return nil
+}
+
+// globsFlag is a flag that can be given multiple times.
+type globsFlag []string
+
+func (g *globsFlag) String() string {
+	return strings.Join(*g, ", ")
+}
+
+func (g *globsFlag) Set(v string) error {
+	*g = append(*g, v)
+	return nil
+}
+
+func (g *globsFlag) values() []string {
+	return *g
+}
+
+// collectInputs returns the files to be converted. Directory arguments are walked
+// recursively for files with the given extensions, and the directory structure
+// inside them is mirrored in the output directory. If there are no arguments, the
+// files matching the glob patterns are returned instead, each file only once, even
+// if it matches multiple patterns.
+func collectInputs(args []string, globs []string, exts []string) ([]input, error) {
+	var inputs []input
+
+	if len(args) == 0 {
+		seen := map[string]bool{}
+		for _, pattern := range globs {
+			matches, err := glob(pattern)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, m := range matches {
+				if p := filepath.Clean(m.path); !seen[p] {
+					seen[p] = true
+					inputs = append(inputs, m)
+				}
+			}
+		}
+
+		return inputs, nil
+	}
+
+	for _, arg := range args {
+		if arg == stdinPath {
+			inputs = append(inputs, input{path: arg})
+			continue
+		}
+
+		info, err := os.Stat(arg)
+		if err != nil || !info.IsDir() {
+			// Errors are reported when the file is converted.
+			inputs = append(inputs, input{path: arg})
+			continue
+		}
+
+		err = filepath.WalkDir(arg, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !hasExt(p, exts) {
+				return nil
+			}
+
+			inputs = append(inputs, newInput(arg, p))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return inputs, nil
+}
+
+func newInput(base, p string) input {
+	dir, err := filepath.Rel(base, filepath.Dir(p))
+	if err != nil || dir == "." {
+		dir = ""
+	}
+
+	return input{path: p, dir: dir}
+}
+
+// glob returns the files matching the pattern. Besides the syntax of filepath.Match,
+// the pattern may contain "**" path elements, which match zero or more directories.
+// The directories of the matched files are given relative to the longest pattern
+// prefix that contains no special characters.
+func glob(pattern string) ([]input, error) {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+
+	var static int
+	for static < len(segments)-1 && !hasMeta(segments[static]) {
+		static++
+	}
+
+	base := filepath.FromSlash(strings.Join(segments[:static], "/"))
+	if base == "" {
+		if static > 0 {
+			// The pattern is absolute.
+			base = string(filepath.Separator)
+		} else {
+			base = "."
+		}
+	}
+	segments = segments[static:]
+
+	var inputs []input
+
+	if !hasRecursiveWildcard(segments) {
+		matches, err := filepath.Glob(pattern)
+		for _, m := range matches {
+			inputs = append(inputs, newInput(base, m))
+		}
+		return inputs, err
+	}
+
+	for _, s := range segments {
+		if _, err := path.Match(s, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	err := filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == base && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(base, p)
+		if err != nil {
+			return err
+		}
+
+		if matchSegments(segments, strings.Split(filepath.ToSlash(rel), "/")) {
+			inputs = append(inputs, newInput(base, p))
+		}
+
+		return nil
+	})
+
+	return inputs, err
+}
+
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, `*?[\`)
+}
+
+func hasRecursiveWildcard(segments []string) bool {
+	for _, s := range segments {
+		if s == "**" {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// samePath reports whether the paths refer to the same file.
+func samePath(a, b string) bool {
+	a, errA := filepath.Abs(a)
+	b, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && a == b
+}
+
+// hasExt reports whether the path has one of the given extensions,
+// ignoring the case.
+func hasExt(p string, exts []string) bool {
+	ext := filepath.Ext(p)
+	for _, e := range exts {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// dotExts are the extensions of the files read as Graphviz DOT files.
+var dotExts = []string{".dot", ".gv"}
+
+// graphmlExts are the extensions of the files read as GraphML files.
+var graphmlExts = []string{".graphml"}
+
+// graphExts are the extensions of the files searched for in directories,
+// when no input format is given.
+var graphExts = append(append([]string{".xml"}, dotExts...), graphmlExts...)
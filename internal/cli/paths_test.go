@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testTree = []string{
+	"a.xml",
+	"a.txt",
+	"other/f.xml",
+	"sub/b.xml",
+	"sub/c.dot",
+	"sub/deep/d.xml",
+	"sub/deep/e.graphml",
+	"sub/deep/g.in",
+}
+
+// chdirTestTree creates the test tree in a temporary directory and makes it
+// the working directory until the test ends.
+func chdirTestTree(t *testing.T) {
+	dir := t.TempDir()
+	for _, p := range testTree {
+		p = filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// testInputs builds the expected inputs from "path:dir" pairs.
+func testInputs(pairs ...string) []input {
+	var inputs []input
+	for _, pair := range pairs {
+		i := strings.IndexByte(pair, ':')
+		p, dir := pair[:i], pair[i+1:]
+		inputs = append(inputs, input{path: filepath.FromSlash(p), dir: filepath.FromSlash(dir)})
+	}
+	return inputs
+}
+
+func TestCollectInputs(t *testing.T) {
+	chdirTestTree(t)
+
+	tests := []struct {
+		name     string
+		args     []string
+		globs    []string
+		exts     []string
+		expected []input
+	}{
+		{
+			name:     "recursive wildcard at the start",
+			globs:    []string{"**/*.xml"},
+			expected: testInputs("a.xml:", "other/f.xml:other", "sub/b.xml:sub", "sub/deep/d.xml:sub/deep"),
+		},
+		{
+			name:     "recursive wildcard in the middle",
+			globs:    []string{"sub/**/*.xml"},
+			expected: testInputs("sub/b.xml:", "sub/deep/d.xml:deep"),
+		},
+		{
+			name:     "recursive wildcard at the end",
+			globs:    []string{"sub/**"},
+			expected: testInputs("sub/b.xml:", "sub/c.dot:", "sub/deep/d.xml:deep", "sub/deep/e.graphml:deep", "sub/deep/g.in:deep"),
+		},
+		{
+			name:     "recursive wildcard matching no directories",
+			globs:    []string{"sub/**/b.xml"},
+			expected: testInputs("sub/b.xml:"),
+		},
+		{
+			name:     "no recursive wildcard",
+			globs:    []string{"*/deep/*.xml"},
+			expected: testInputs("sub/deep/d.xml:sub/deep"),
+		},
+		{
+			name:     "overlapping globs",
+			globs:    []string{"**/*.xml", "sub/*.xml", "./sub/deep/*"},
+			expected: testInputs("a.xml:", "other/f.xml:other", "sub/b.xml:sub", "sub/deep/d.xml:sub/deep", "sub/deep/e.graphml:", "sub/deep/g.in:"),
+		},
+		{
+			name:     "missing base directory",
+			globs:    []string{"missing/**/*.xml"},
+			expected: nil,
+		},
+		{
+			name:     "directory walk",
+			args:     []string{"sub", "a.txt", "-", "missing.xml"},
+			globs:    []string{"**/*.xml"},
+			exts:     graphExts,
+			expected: testInputs("sub/b.xml:", "sub/c.dot:", "sub/deep/d.xml:deep", "sub/deep/e.graphml:deep", "a.txt:", "-:", "missing.xml:"),
+		},
+		{
+			name:     "directory walk with input format",
+			args:     []string{"."},
+			exts:     []string{".in"},
+			expected: testInputs("sub/deep/g.in:sub/deep"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inputs, err := collectInputs(test.args, test.globs, test.exts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(inputs, test.expected) {
+				t.Fatalf("Invalid inputs:\nexpected %+v\nreceived %+v", test.expected, inputs)
+			}
+		})
+	}
+
+	for _, pattern := range []string{"[", "**/[", "sub/**/a[.xml"} {
+		if _, err := collectInputs(nil, []string{pattern}, nil); err == nil {
+			t.Fatalf("Expected error for pattern %q", pattern)
+		}
+	}
+}
+
+func TestOutputPathsClaim(t *testing.T) {
+	var o outputPaths
+
+	if err := o.claim("out/a.in", "a.xml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.claim("out/sub/../a.in", "a.xml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.claim("out/b.in", "b/a.xml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.claim("./out/a.in", "b/a.xml"); err == nil {
+		t.Fatal("Expected error for an output path claimed by another input")
+	} else {
+		t.Log(err)
+	}
+}
+
+func TestSamePath(t *testing.T) {
+	chdirTestTree(t)
+
+	wd, _ := os.Getwd()
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"a.xml", "a.xml", true},
+		{"a.xml", "./sub/../a.xml", true},
+		{"a.xml", filepath.Join(wd, "a.xml"), true},
+		{"a.xml", "a.txt", false},
+		{"sub/b.xml", "b.xml", false},
+	}
+
+	for _, test := range tests {
+		if samePath(test.a, test.b) != test.expected {
+			t.Fatalf("samePath(%q, %q) should be %t", test.a, test.b, test.expected)
+		}
+	}
+}
+
+func TestHasExt(t *testing.T) {
+	for _, p := range []string{"a.xml", "a.XML", "dir.gv/a.Dot", "a.graphml"} {
+		if !hasExt(p, graphExts) {
+			t.Fatalf("%q should have a graph extension", p)
+		}
+	}
+	for _, p := range []string{"a.in", "xml", "a.xml.bak", "a."} {
+		if hasExt(p, graphExts) {
+			t.Fatalf("%q shouldn't have a graph extension", p)
+		}
+	}
+}
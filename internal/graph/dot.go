@@ -0,0 +1,833 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// dotPrinter is the printer used by ToDOT.
+var dotPrinter = MustParsePrinter("%G\n", PreserveIDs())
+
+// ToDOT writes the graph in the DOT language of Graphviz, keeping the node IDs
+// as they are. It is the same as printing the graph with the "%G" verb.
+func ToDOT(w io.Writer, g *Graph) error {
+	_, err := dotPrinter.Print(w, g)
+	return err
+}
+
+// dotOperation prints the graph in the DOT language. Graphs with only undirected
+// edges are written as a "graph", the others as a "digraph", where the undirected
+// edges have no arrows. Labels and costs become the labels of the nodes and edges,
+// the costs being also written as "cost" attributes, which FromDOT reads back,
+// and the positions from graph.jar, if the graph has any, are pinned, so that layouts
+// which respect them, such as "neato -n", draw the graph like graph.jar does.
+type dotOperation struct {
+	cost costFunction
+}
+
+func (d *dotOperation) apply(w writer, g *state) (int, error) {
+	digraph := false
+	for i := range g.Edges {
+		if g.Edges[i].Directed {
+			digraph = true
+			break
+		}
+	}
+
+	b := []byte{}
+	edgeOp := " -- "
+	if digraph {
+		b = append(b, "digraph {\n"...)
+		edgeOp = " -> "
+	} else {
+		b = append(b, "graph {\n"...)
+	}
+
+	placed := g.hasLayout()
+	var attrs []string
+	for i := range g.Nodes {
+		nd := &g.Nodes[i]
+		attrs = attrs[:0]
+		if nd.Label != "" {
+			attrs = append(attrs, "label="+dotQuote(nd.Label))
+		}
+		if nd.Cost != 0 {
+			cost := dotQuote(string(d.cost.append(nil, nd.Cost)))
+			attrs = append(attrs, "xlabel="+cost, "cost="+cost)
+		}
+		if placed {
+			c := nd.Graphics.Center
+			// The y axis of graph.jar points down, while the one of Graphviz points up.
+			pos := formatXMLFloat(c.X) + "," + formatXMLFloat(-c.Y) + "!"
+			attrs = append(attrs, "pos="+dotQuote(pos))
+		}
+
+		b = append(b, "  "...)
+		b = strconv.AppendInt(b, int64(g.id(nd.ID)), 10)
+		b = appendDOTAttrs(b, attrs)
+	}
+
+	for i := range g.Edges {
+		e := &g.Edges[i]
+		attrs = attrs[:0]
+		if digraph && !bool(e.Directed) {
+			attrs = append(attrs, "dir=none")
+		}
+
+		label := e.Label
+		if e.Cost != 0 {
+			cost := string(d.cost.append(nil, e.Cost))
+			if label != "" {
+				label += " (" + cost + ")"
+			} else {
+				label = cost
+			}
+		}
+		if label != "" {
+			attrs = append(attrs, "label="+dotQuote(label))
+		}
+		if e.Cost != 0 {
+			attrs = append(attrs, "cost="+dotQuote(string(d.cost.append(nil, e.Cost))))
+		}
+
+		b = append(b, "  "...)
+		b = strconv.AppendInt(b, int64(g.id(e.Src)), 10)
+		b = append(b, edgeOp...)
+		b = strconv.AppendInt(b, int64(g.id(e.Dst)), 10)
+		b = appendDOTAttrs(b, attrs)
+	}
+
+	b = append(b, '}')
+
+	return w.Write(b)
+}
+
+func appendDOTAttrs(b []byte, attrs []string) []byte {
+	if len(attrs) > 0 {
+		b = append(b, " ["...)
+		b = append(b, strings.Join(attrs, ", ")...)
+		b = append(b, ']')
+	}
+	return append(b, ";\n"...)
+}
+
+var dotReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+
+// dotQuote returns the text as a DOT string.
+func dotQuote(s string) string {
+	return `"` + dotReplacer.Replace(s) + `"`
+}
+
+// FromDOT reads a graph written in the DOT language of Graphviz. Both graphs
+// and digraphs are read, the edges of a digraph being directed unless they
+// have the "dir=none" attribute. Subgraphs are flattened into the graph, and
+// the default attributes given with "node [...]" and "edge [...]" are applied.
+//
+// Nodes named by non-negative integers keep them as IDs. The other nodes are
+// given the next unused IDs, in the order they appear, and their names are
+// kept as their labels unless they have a "label" attribute. The "label"
+// attribute becomes the label of a node or edge, the "cost" attribute, or the
+// "weight" attribute if there is none, its cost, and the "pos" attribute the
+// center of a node, as written by ToDOT. The cost which ToDOT adds to the edge
+// labels is removed from them when the edge has a "cost" attribute.
+func FromDOT(r io.Reader) (Graph, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Graph{}, err
+	}
+
+	p := &dotParser{lex: dotLexer{in: string(data), line: 1}, index: map[string]int{}}
+	if err := p.parse(); err != nil {
+		return Graph{}, err
+	}
+
+	return p.graph()
+}
+
+type dotTokenKind int
+
+const (
+	dotEOF dotTokenKind = iota
+	// dotID is an identifier, a number or a quoted or HTML string.
+	dotID
+	// dotPunct is one of the characters "{}[]=;,:".
+	dotPunct
+	// dotEdgeOp is "--" or "->".
+	dotEdgeOp
+)
+
+type dotToken struct {
+	kind dotTokenKind
+	text string
+	// quoted tells if the identifier was a string, so it isn't a keyword.
+	quoted bool
+	line   int
+}
+
+// is reports whether the token is the given keyword, which is case-insensitive,
+// or the given punctuation.
+func (t dotToken) is(text string) bool {
+	switch t.kind {
+	case dotID:
+		return !t.quoted && strings.EqualFold(t.text, text)
+	case dotPunct, dotEdgeOp:
+		return t.text == text
+	default:
+		return false
+	}
+}
+
+type dotLexer struct {
+	in   string
+	pos  int
+	line int
+}
+
+func (l *dotLexer) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("dot: line %d: %s", l.line, fmt.Sprintf(format, args...))
+}
+
+// skip skips whitespace, comments and preprocessor lines.
+func (l *dotLexer) skip() {
+	lineStart := l.pos == 0
+	for l.pos < len(l.in) {
+		switch c := l.in[l.pos]; {
+		case c == '\n':
+			l.line++
+			l.pos++
+			lineStart = true
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+		case c == '#' && lineStart:
+			l.skipUntil("\n")
+		case strings.HasPrefix(l.in[l.pos:], "//"):
+			l.skipUntil("\n")
+		case strings.HasPrefix(l.in[l.pos:], "/*"):
+			l.skipUntil("*/")
+			l.pos += len("*/")
+			if l.pos > len(l.in) {
+				l.pos = len(l.in)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *dotLexer) skipUntil(end string) {
+	i := strings.Index(l.in[l.pos:], end)
+	if i == -1 {
+		i = len(l.in) - l.pos
+	}
+	l.line += strings.Count(l.in[l.pos:l.pos+i], "\n")
+	l.pos += i
+}
+
+func isDOTLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (l *dotLexer) next() (dotToken, error) {
+	l.skip()
+	if l.pos == len(l.in) {
+		return dotToken{kind: dotEOF, line: l.line}, nil
+	}
+
+	t := dotToken{kind: dotID, line: l.line}
+	start := l.pos
+	c := l.in[l.pos]
+
+	switch {
+	case strings.HasPrefix(l.in[l.pos:], "--") || strings.HasPrefix(l.in[l.pos:], "->"):
+		l.pos += 2
+		t.kind = dotEdgeOp
+	case strings.IndexByte("{}[]=;,:", c) != -1:
+		l.pos++
+		t.kind = dotPunct
+	case isDOTLetter(c):
+		for l.pos < len(l.in) && (isDOTLetter(l.in[l.pos]) || isDigit(l.in[l.pos])) {
+			l.pos++
+		}
+	case isDigit(c) || c == '-' || c == '.':
+		l.pos++
+		for l.pos < len(l.in) && (isDigit(l.in[l.pos]) || l.in[l.pos] == '.') {
+			l.pos++
+		}
+		if t.text = l.in[start:l.pos]; t.text == "-" || t.text == "." || t.text == "-." {
+			return t, l.errorf("invalid number %q", t.text)
+		}
+		return t, nil
+	case c == '"':
+		return l.quoted()
+	case c == '<':
+		return l.html()
+	default:
+		return t, l.errorf("unexpected character %q", c)
+	}
+
+	t.text = l.in[start:l.pos]
+	return t, nil
+}
+
+// quoted reads a double-quoted string. Escaped quotes are unescaped and escaped
+// line breaks are removed. Other escape sequences, which Graphviz interprets
+// in labels, are kept as they are, except for "\n" which becomes a line break.
+func (l *dotLexer) quoted() (dotToken, error) {
+	t := dotToken{kind: dotID, quoted: true, line: l.line}
+	var sb strings.Builder
+
+	for l.pos++; l.pos < len(l.in); l.pos++ {
+		c := l.in[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			t.text = sb.String()
+			return t, nil
+		case c == '\\' && l.pos+1 < len(l.in):
+			l.pos++
+			switch e := l.in[l.pos]; e {
+			case '"', '\\':
+				sb.WriteByte(e)
+			case 'n':
+				sb.WriteByte('\n')
+			case '\n':
+				l.line++
+			case '\r':
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(e)
+			}
+		default:
+			if c == '\n' {
+				l.line++
+			}
+			sb.WriteByte(c)
+		}
+	}
+
+	return t, l.errorf("unterminated string")
+}
+
+// html reads an HTML string, which is delimited by balanced angle brackets.
+func (l *dotLexer) html() (dotToken, error) {
+	t := dotToken{kind: dotID, quoted: true, line: l.line}
+	start := l.pos + 1
+	depth := 0
+
+	for ; l.pos < len(l.in); l.pos++ {
+		switch l.in[l.pos] {
+		case '<':
+			depth++
+		case '>':
+			if depth--; depth == 0 {
+				t.text = l.in[start:l.pos]
+				l.pos++
+				return t, nil
+			}
+		case '\n':
+			l.line++
+		}
+	}
+
+	return t, l.errorf("unterminated HTML string")
+}
+
+// dotNode is a node found while parsing, named as in the DOT file.
+type dotNode struct {
+	name  string
+	attrs map[string]string
+}
+
+type dotEdge struct {
+	src, dst int
+	directed bool
+	attrs    map[string]string
+}
+
+type dotParser struct {
+	lex     dotLexer
+	peeked  *dotToken
+	digraph bool
+
+	nodes []dotNode
+	// index maps the names of the nodes to their position in nodes.
+	index map[string]int
+	edges []dotEdge
+}
+
+func (p *dotParser) next() (dotToken, error) {
+	if t := p.peeked; t != nil {
+		p.peeked = nil
+		return *t, nil
+	}
+	return p.lex.next()
+}
+
+func (p *dotParser) peek() (dotToken, error) {
+	if p.peeked == nil {
+		t, err := p.lex.next()
+		if err != nil {
+			return t, err
+		}
+		p.peeked = &t
+	}
+	return *p.peeked, nil
+}
+
+func (p *dotParser) errorf(t dotToken, format string, args ...interface{}) error {
+	return fmt.Errorf("dot: line %d: %s", t.line, fmt.Sprintf(format, args...))
+}
+
+func (p *dotParser) expect(text string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if !t.is(text) {
+		return p.errorf(t, "expected %q, found %q", text, t.text)
+	}
+	return nil
+}
+
+// id reads an identifier. Strings joined with "+" are concatenated.
+func (p *dotParser) id() (string, error) {
+	t, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	if t.kind != dotID {
+		return "", p.errorf(t, "expected an identifier, found %q", t.text)
+	}
+
+	text := t.text
+	for t.quoted {
+		// The lexer has no token for "+", which can only follow strings.
+		p.lex.skip()
+		if !strings.HasPrefix(p.lex.in[p.lex.pos:], "+") || p.peeked != nil {
+			break
+		}
+		p.lex.pos++
+		if t, err = p.next(); err != nil {
+			return "", err
+		}
+		if t.kind != dotID || !t.quoted {
+			return "", p.errorf(t, "expected a string after \"+\", found %q", t.text)
+		}
+		text += t.text
+	}
+
+	return text, nil
+}
+
+func (p *dotParser) parse() error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.is("strict") {
+		if t, err = p.next(); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case t.is("digraph"):
+		p.digraph = true
+	case t.is("graph"):
+	default:
+		return p.errorf(t, "expected \"graph\" or \"digraph\", found %q", t.text)
+	}
+
+	if t, err = p.peek(); err != nil {
+		return err
+	}
+	if t.kind == dotID {
+		if _, err := p.id(); err != nil {
+			return err
+		}
+	}
+
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	if _, err := p.stmts(map[string]string{}, map[string]string{}); err != nil {
+		return err
+	}
+
+	if t, err = p.next(); err != nil {
+		return err
+	}
+	if t.kind != dotEOF {
+		return p.errorf(t, "unexpected %q after the graph", t.text)
+	}
+
+	return nil
+}
+
+// stmts reads the statements up to the closing brace, with the given default
+// attributes of the nodes and edges. It returns the nodes used by the statements.
+func (p *dotParser) stmts(nodeAttrs, edgeAttrs map[string]string) ([]int, error) {
+	var used []int
+
+	for {
+		t, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case t.kind == dotEOF:
+			return nil, p.errorf(t, "expected \"}\"")
+		case t.is("}"):
+			p.next()
+			return used, nil
+		case t.is(";"):
+			p.next()
+			continue
+		case t.is("graph") || t.is("node") || t.is("edge"):
+			p.next()
+			attrs, err := p.attrs()
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case t.is("node"):
+				nodeAttrs = merge(nodeAttrs, attrs)
+			case t.is("edge"):
+				edgeAttrs = merge(edgeAttrs, attrs)
+			}
+			continue
+		}
+
+		var left []int
+		if t.is("subgraph") || t.is("{") {
+			if left, err = p.subgraph(nodeAttrs, edgeAttrs); err != nil {
+				return nil, err
+			}
+		} else {
+			name, err := p.id()
+			if err != nil {
+				return nil, err
+			}
+			if t, err = p.peek(); err != nil {
+				return nil, err
+			}
+			if t.is("=") {
+				// A graph attribute.
+				p.next()
+				if _, err := p.id(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			if err := p.port(); err != nil {
+				return nil, err
+			}
+			left = []int{p.node(name, nodeAttrs)}
+
+			if t, err = p.peek(); err != nil {
+				return nil, err
+			}
+			if t.kind != dotEdgeOp {
+				attrs, err := p.attrs()
+				if err != nil {
+					return nil, err
+				}
+				n := &p.nodes[left[0]]
+				n.attrs = merge(n.attrs, attrs)
+			}
+		}
+
+		used = append(used, left...)
+		edgeUsed, err := p.edgeStmt(left, nodeAttrs, edgeAttrs)
+		if err != nil {
+			return nil, err
+		}
+		used = append(used, edgeUsed...)
+	}
+}
+
+// edgeStmt reads the rest of an edge statement starting with the given nodes,
+// if there is one, and returns the other nodes it uses.
+func (p *dotParser) edgeStmt(left []int, nodeAttrs, edgeAttrs map[string]string) ([]int, error) {
+	var used []int
+	var ops []dotToken
+	operands := [][]int{left}
+
+	for {
+		t, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if t.kind != dotEdgeOp {
+			break
+		}
+		p.next()
+		if t.text == "->" && !p.digraph || t.text == "--" && p.digraph {
+			return nil, p.errorf(t, "edge operator %q is not allowed in this graph", t.text)
+		}
+		ops = append(ops, t)
+
+		if t, err = p.peek(); err != nil {
+			return nil, err
+		}
+
+		var right []int
+		if t.is("subgraph") || t.is("{") {
+			right, err = p.subgraph(nodeAttrs, edgeAttrs)
+		} else {
+			var name string
+			if name, err = p.id(); err == nil {
+				err = p.port()
+			}
+			right = []int{p.node(name, nodeAttrs)}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		used = append(used, right...)
+		operands = append(operands, right)
+	}
+
+	if len(ops) == 0 {
+		return nil, nil
+	}
+
+	attrs, err := p.attrs()
+	if err != nil {
+		return nil, err
+	}
+	attrs = merge(edgeAttrs, attrs)
+
+	for i := 1; i < len(operands); i++ {
+		for _, src := range operands[i-1] {
+			for _, dst := range operands[i] {
+				p.edges = append(p.edges, dotEdge{
+					src:      src,
+					dst:      dst,
+					directed: p.digraph && attrs["dir"] != "none",
+					attrs:    attrs,
+				})
+			}
+		}
+	}
+
+	return used, nil
+}
+
+// subgraph reads a subgraph, whose statements are flattened into the graph,
+// and returns the nodes it uses.
+func (p *dotParser) subgraph(nodeAttrs, edgeAttrs map[string]string) ([]int, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if t.is("subgraph") {
+		if t, err = p.peek(); err != nil {
+			return nil, err
+		}
+		if t.kind == dotID {
+			if _, err := p.id(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect("{"); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.stmts(nodeAttrs, edgeAttrs)
+}
+
+// port skips the port of a node, if there is one.
+func (p *dotParser) port() error {
+	for i := 0; i < 2; i++ {
+		t, err := p.peek()
+		if err != nil || !t.is(":") {
+			return err
+		}
+		p.next()
+		if _, err := p.id(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attrs reads the attribute lists at the current position, if there are any.
+func (p *dotParser) attrs() (map[string]string, error) {
+	var attrs map[string]string
+
+	for {
+		t, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if !t.is("[") {
+			return attrs, nil
+		}
+		p.next()
+
+		if attrs == nil {
+			attrs = map[string]string{}
+		}
+
+		for {
+			if t, err = p.peek(); err != nil {
+				return nil, err
+			}
+			if t.is("]") {
+				p.next()
+				break
+			}
+			if t.is(",") || t.is(";") {
+				p.next()
+				continue
+			}
+
+			name, err := p.id()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+			value, err := p.id()
+			if err != nil {
+				return nil, err
+			}
+			attrs[name] = value
+		}
+	}
+}
+
+// merge returns the attributes of a with the ones of b added.
+func merge(a, b map[string]string) map[string]string {
+	if len(b) == 0 {
+		return a
+	}
+
+	m := make(map[string]string, len(a)+len(b))
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+	return m
+}
+
+// node returns the position of the node with the given name, adding it
+// with the given default attributes if it wasn't used before.
+func (p *dotParser) node(name string, attrs map[string]string) int {
+	if i, ok := p.index[name]; ok {
+		return i
+	}
+
+	p.index[name] = len(p.nodes)
+	p.nodes = append(p.nodes, dotNode{name: name, attrs: attrs})
+	return len(p.nodes) - 1
+}
+
+func (p *dotParser) graph() (Graph, error) {
+	g := Graph{Nodes: make([]Node, len(p.nodes)), Edges: make([]Edge, len(p.edges))}
+
+	names := make([]string, len(p.nodes))
+	for i, n := range p.nodes {
+		names[i] = n.name
+	}
+	ids, named := idsFromNames(names)
+
+	for i, n := range p.nodes {
+		nd := &g.Nodes[i]
+		nd.ID = ids[i]
+		if !named[i] {
+			nd.Label = n.name
+		}
+
+		if label, ok := n.attrs["label"]; ok {
+			nd.Label = label
+		}
+		if err := attrCost(n.attrs, &nd.Cost); err != nil {
+			return Graph{}, fmt.Errorf("dot: node %q: %w", n.name, err)
+		}
+		if pos, ok := n.attrs["pos"]; ok {
+			if err := dotPos(pos, &nd.Graphics.Center); err != nil {
+				return Graph{}, fmt.Errorf("dot: node %q: %w", n.name, err)
+			}
+		}
+	}
+
+	for i, e := range p.edges {
+		ge := &g.Edges[i]
+		ge.Src, ge.Dst = g.Nodes[e.src].ID, g.Nodes[e.dst].ID
+		ge.Directed = directed(e.directed)
+		ge.Label = dotEdgeLabel(e.attrs)
+		if err := attrCost(e.attrs, &ge.Cost); err != nil {
+			return Graph{}, fmt.Errorf("dot: edge %q -> %q: %w", p.nodes[e.src].name, p.nodes[e.dst].name, err)
+		}
+	}
+
+	return g, nil
+}
+
+// dotEdgeLabel returns the label of an edge, without the cost which ToDOT adds
+// to the labels of the edges that have a "cost" attribute.
+func dotEdgeLabel(attrs map[string]string) string {
+	label := attrs["label"]
+	cost, ok := attrs["cost"]
+	if !ok {
+		return label
+	}
+	if label == cost {
+		return ""
+	}
+	return strings.TrimSuffix(label, " ("+cost+")")
+}
+
+// attrCost reads the cost from the "cost" attribute or, if it is missing,
+// from the "weight" attribute.
+func attrCost(attrs map[string]string, cost *float64) error {
+	v, ok := attrs["cost"]
+	if !ok {
+		if v, ok = attrs["weight"]; !ok {
+			return nil
+		}
+	}
+
+	var err error
+	if *cost, err = strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil {
+		return fmt.Errorf("invalid cost %q", v)
+	}
+	return nil
+}
+
+// dotPos reads a position written as "x,y", optionally followed by "!".
+// The y axis of Graphviz points up, while the one of graph.jar points down.
+func dotPos(pos string, p *Point) error {
+	parts := strings.Split(strings.TrimSuffix(strings.TrimSpace(pos), "!"), ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return fmt.Errorf("invalid position %q", pos)
+	}
+
+	var err error
+	if p.X, err = strconv.ParseFloat(parts[0], 64); err == nil {
+		p.Y, err = strconv.ParseFloat(parts[1], 64)
+		p.Y = -p.Y
+	}
+	if err == nil && len(parts) == 3 {
+		p.Z, err = strconv.ParseFloat(parts[2], 64)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid position %q", pos)
+	}
+	return nil
+}
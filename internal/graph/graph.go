@@ -6,16 +6,35 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 	"unsafe"
 
 	xmlparser "github.com/tamerh/xml-stream-parser"
 )
 
+// Point is a position in the three-dimensional drawing space of graph.jar.
+type Point struct {
+	X float64 `xml:"x"`
+	Y float64 `xml:"y"`
+	Z float64 `xml:"z"`
+}
+
+// Graphics describes how graph.jar draws a node: its center and its size.
+type Graphics struct {
+	Center Point   `xml:"center"`
+	Width  float64 `xml:"width"`
+	Height float64 `xml:"height"`
+	Depth  float64 `xml:"depth"`
+}
+
 type Node struct {
-	ID   int     `xml:"id,attr,string"`
-	Cost float64 `xml:"cost"`
+	ID       int      `xml:"id,attr,string"`
+	Cost     float64  `xml:"cost"`
+	Label    string   `xml:"label"`
+	Graphics Graphics `xml:"graphics"`
 }
 
 func (n *Node) unmarshalXML(dec *xml.Decoder, s xml.StartElement) error {
@@ -31,7 +50,10 @@ func (n *Node) unmarshalXML(dec *xml.Decoder, s xml.StartElement) error {
 		return fmt.Errorf("node has invalid id value: %w", err)
 	}
 
-	var inCost bool
+	var inGraphics, inCenter bool
+	var num *float64
+	var str *string
+	var name string
 	var depth int
 
 	for {
@@ -43,22 +65,64 @@ func (n *Node) unmarshalXML(dec *xml.Decoder, s xml.StartElement) error {
 		switch t := it.(type) {
 		case xml.StartElement:
 			depth++
-			inCost = t.Name.Local == "cost" && depth == 1
-		case xml.CharData:
-			if !inCost {
-				break
+			num, str, name = nil, nil, t.Name.Local
+
+			switch {
+			case depth == 1:
+				switch name {
+				case "cost":
+					num = &n.Cost
+				case "label":
+					str = &n.Label
+				case "graphics":
+					inGraphics = true
+				}
+			case depth == 2 && inGraphics:
+				switch name {
+				case "center":
+					inCenter = true
+				case "width":
+					num = &n.Graphics.Width
+				case "height":
+					num = &n.Graphics.Height
+				case "depth":
+					num = &n.Graphics.Depth
+				}
+			case depth == 3 && inCenter:
+				switch name {
+				case "x":
+					num = &n.Graphics.Center.X
+				case "y":
+					num = &n.Graphics.Center.Y
+				case "z":
+					num = &n.Graphics.Center.Z
+				}
 			}
+		case xml.CharData:
+			if str != nil {
+				// CharData is only valid until the next token is read, so it must be copied.
+				*str += strings.TrimSpace(string(t))
+			} else if num != nil {
+				*num, err = strconv.ParseFloat(strings.TrimSpace(*(*string)(unsafe.Pointer(&t))), 64)
+				if err != nil {
+					return fmt.Errorf("node has invalid %s: %w", name, err)
+				}
 
-			n.Cost, err = strconv.ParseFloat(strings.TrimSpace(*(*string)(unsafe.Pointer(&t))), 64)
-			if err != nil {
-				return fmt.Errorf("node has invalid cost: %w", err)
+				num = nil
 			}
-
-			inCost = false
 		case xml.EndElement:
 			depth--
-			if t.Name.Local == "node" && depth == -1 {
-				return nil
+			num, str = nil, nil
+
+			switch t.Name.Local {
+			case "graphics":
+				inGraphics = false
+			case "center":
+				inCenter = false
+			case "node":
+				if depth == -1 {
+					return nil
+				}
 			}
 		}
 	}
@@ -74,6 +138,7 @@ func (d *directed) UnmarshalXMLAttr(a xml.Attr) error {
 
 type Edge struct {
 	Cost     float64  `xml:"cost"`
+	Label    string   `xml:"label"`
 	Directed directed `xml:"directed,attr"`
 	Src      int      `xml:"source"`
 	Dst      int      `xml:"target"`
@@ -84,7 +149,7 @@ func (e *Edge) unmarshalXML(dec *xml.Decoder, s xml.StartElement) error {
 		e.Directed = directed.Value == "yes"
 	}
 
-	var inCost, inSrc, inDst bool
+	var inCost, inLabel, inSrc, inDst bool
 	var depth int
 
 	for {
@@ -102,6 +167,7 @@ func (e *Edge) unmarshalXML(dec *xml.Decoder, s xml.StartElement) error {
 
 			n := t.Name.Local
 			inCost = n == "cost"
+			inLabel = n == "label"
 			inSrc = n == "source"
 			inDst = n == "target"
 		case xml.CharData:
@@ -112,6 +178,9 @@ func (e *Edge) unmarshalXML(dec *xml.Decoder, s xml.StartElement) error {
 					return fmt.Errorf("edge has invalid cost: %w", err)
 				}
 				inCost = false
+			} else if inLabel {
+				// CharData is only valid until the next token is read, so it must be copied.
+				e.Label += strings.TrimSpace(string(t))
 			} else if inSrc || inDst {
 				v, err := strconv.Atoi(strings.TrimSpace(s))
 				if err != nil {
@@ -128,6 +197,7 @@ func (e *Edge) unmarshalXML(dec *xml.Decoder, s xml.StartElement) error {
 			}
 		case xml.EndElement:
 			depth--
+			inLabel = false
 			if t.Name.Local == "edge" && depth == -1 {
 				return nil
 			}
@@ -140,25 +210,57 @@ type Graph struct {
 	Edges []Edge `xml:"edge"`
 }
 
+// hasLayout reports whether any node of the graph has a position. Graphs read
+// from formats without positions have all their nodes at the origin, while in
+// a graph which has a layout a node at the origin was really drawn there.
+func (g *Graph) hasLayout() bool {
+	for i := range g.Nodes {
+		if g.Nodes[i].Graphics.Center != (Point{}) {
+			return true
+		}
+	}
+	return false
+}
+
+// errNoGraph is returned by the XML readers for documents without a graph element.
+var errNoGraph = errors.New("no graph element found")
+
+// errGraphNotClosed is returned by the XML readers for documents which end
+// before the graph element is closed.
+var errGraphNotClosed = fmt.Errorf("graph element is not closed: %w", io.ErrUnexpectedEOF)
+
 func FromXML(r io.Reader) (Graph, error) {
 	var g Graph
+	var opened, closed bool
 	dec := xml.NewDecoder(r)
 
 	for {
 		it, err := dec.RawToken()
 		if err == io.EOF {
+			if !opened {
+				return Graph{}, errNoGraph
+			}
+			if !closed {
+				return Graph{}, errGraphNotClosed
+			}
 			return g, nil
 		}
 		if err != nil {
 			return Graph{}, err
 		}
 
+		if t, ok := it.(xml.EndElement); ok && t.Name.Local == "graph" {
+			closed = true
+		}
+
 		t, ok := it.(xml.StartElement)
 		if !ok {
 			continue
 		}
 
 		switch t.Name.Local {
+		case "graph":
+			opened = true
 		case "node":
 			var node Node
 			if err := node.unmarshalXML(dec, t); err != nil {
@@ -185,13 +287,65 @@ func getChild(children map[string][]xmlparser.XMLElement, name string) *xmlparse
 	return &arr[0]
 }
 
+func parseChildFloat(e *xmlparser.XMLElement, name string, v *float64) error {
+	c := getChild(e.Childs, name)
+	if c == nil {
+		return nil
+	}
+
+	var err error
+	*v, err = strconv.ParseFloat(strings.TrimSpace(c.InnerText), 64)
+	if err != nil {
+		return fmt.Errorf("node has invalid %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func (gr *Graphics) fromElement(e *xmlparser.XMLElement) error {
+	if center := getChild(e.Childs, "center"); center != nil {
+		for _, f := range []struct {
+			name string
+			v    *float64
+		}{{"x", &gr.Center.X}, {"y", &gr.Center.Y}, {"z", &gr.Center.Z}} {
+			if err := parseChildFloat(center, f.name, f.v); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, f := range []struct {
+		name string
+		v    *float64
+	}{{"width", &gr.Width}, {"height", &gr.Height}, {"depth", &gr.Depth}} {
+		if err := parseChildFloat(e, f.name, f.v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func FromXMLNoStd(r *bufio.Reader) (Graph, error) {
-	p := xmlparser.NewXMLParser(r, "node", "edge")
+	// The closing tag of the graph is streamed as an element named "/graph".
+	p := xmlparser.NewXMLParser(r, "graph", "/graph", "node", "edge").ParseAttributesOnly("graph", "/graph")
 	var g Graph
+	var opened, closed bool
 	var err error
 
 	for e := range p.Stream() {
+		if e.Err == io.EOF {
+			return Graph{}, io.ErrUnexpectedEOF
+		}
+		if e.Err != nil {
+			return Graph{}, e.Err
+		}
+
 		switch e.Name {
+		case "graph":
+			opened = true
+		case "/graph":
+			closed = true
 		case "node":
 			var node Node
 			node.ID, err = strconv.Atoi(e.Attrs["id"])
@@ -206,6 +360,16 @@ func FromXMLNoStd(r *bufio.Reader) (Graph, error) {
 				}
 			}
 
+			if label := getChild(e.Childs, "label"); label != nil {
+				node.Label = innerText(label)
+			}
+
+			if graphics := getChild(e.Childs, "graphics"); graphics != nil {
+				if err := node.Graphics.fromElement(graphics); err != nil {
+					return Graph{}, err
+				}
+			}
+
 			g.Nodes = append(g.Nodes, node)
 		case "edge":
 			var edge Edge
@@ -234,13 +398,95 @@ func FromXMLNoStd(r *bufio.Reader) (Graph, error) {
 				}
 			}
 
+			if label := getChild(e.Childs, "label"); label != nil {
+				edge.Label = innerText(label)
+			}
+
 			g.Edges = append(g.Edges, edge)
 		}
 	}
 
+	if !opened {
+		return Graph{}, errNoGraph
+	}
+	if !closed {
+		return Graph{}, errGraphNotClosed
+	}
+
 	return g, nil
 }
 
+// innerText returns the trimmed text of the element, with the entities
+// such as "&amp;" replaced, which the stream parser doesn't do.
+func innerText(e *xmlparser.XMLElement) string {
+	return unescapeXML(strings.TrimSpace(e.InnerText))
+}
+
+// unescapeXML replaces the predefined XML entities and the character references
+// in s. Other entities are not defined in graph.jar files, so they are kept as
+// they are.
+func unescapeXML(s string) string {
+	if !strings.Contains(s, "&") {
+		return s
+	}
+
+	var sb strings.Builder
+	for {
+		i := strings.IndexByte(s, '&')
+		if i < 0 {
+			break
+		}
+		sb.WriteString(s[:i])
+		s = s[i:]
+
+		j := strings.IndexByte(s, ';')
+		if j < 0 {
+			break
+		}
+		if r, ok := xmlEntity(s[1:j]); ok {
+			sb.WriteString(r)
+			s = s[j+1:]
+		} else {
+			sb.WriteByte('&')
+			s = s[1:]
+		}
+	}
+	sb.WriteString(s)
+
+	return sb.String()
+}
+
+// xmlEntity returns the text of the entity or character reference with the given name.
+func xmlEntity(name string) (string, bool) {
+	switch name {
+	case "amp":
+		return "&", true
+	case "lt":
+		return "<", true
+	case "gt":
+		return ">", true
+	case "quot":
+		return "\"", true
+	case "apos":
+		return "'", true
+	}
+
+	if !strings.HasPrefix(name, "#") {
+		return "", false
+	}
+
+	digits, base := name[1:], 10
+	if strings.HasPrefix(digits, "x") {
+		digits, base = digits[1:], 16
+	}
+	n, err := strconv.ParseUint(digits, base, 32)
+	if err != nil || !utf8.ValidRune(rune(n)) {
+		return "", false
+	}
+
+	return string(rune(n)), true
+}
+
 func findAttribute(attrs []xml.Attr, attr string) *xml.Attr {
 	for i := range attrs {
 		if attrs[i].Name.Local == attr {
@@ -250,3 +496,104 @@ func findAttribute(attrs []xml.Attr, attr string) *xml.Attr {
 
 	return nil
 }
+
+const (
+	// layoutNodeSize is the size of the nodes which don't have one.
+	layoutNodeSize = 20
+	// layoutMargin is the space left around the nodes placed by ToXML.
+	layoutMargin = 50
+	// layoutMinRadius is the smallest radius of the circle on which ToXML places nodes.
+	layoutMinRadius = 150
+)
+
+// ToXML writes the graph in the XML format of graph.jar, which FromXML reads.
+// If no node has a position, that is all the nodes are at the origin, the nodes
+// are placed evenly on a circle in the order they appear in the graph, so the
+// same graph is always drawn the same way. Nodes without a size are given the
+// default size of graph.jar.
+func ToXML(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+	radius := math.Max(layoutMinRadius, float64(len(g.Nodes)*2*layoutNodeSize)/(2*math.Pi))
+	placed := g.hasLayout()
+
+	bw.WriteString(xml.Header[:len(xml.Header)-1])
+	bw.WriteString("<!DOCTYPE graph SYSTEM \"graph.dtd\">\n<graph>\n")
+
+	for i := range g.Nodes {
+		n := &g.Nodes[i]
+		gr := n.Graphics
+		if !placed {
+			angle := 2*math.Pi*float64(i)/float64(len(g.Nodes)) - math.Pi/2
+			gr.Center = Point{
+				X: math.Round(layoutMargin + radius + radius*math.Cos(angle)),
+				Y: math.Round(layoutMargin + radius + radius*math.Sin(angle)),
+			}
+		}
+		if gr.Width == 0 && gr.Height == 0 && gr.Depth == 0 {
+			gr.Width, gr.Height, gr.Depth = layoutNodeSize, layoutNodeSize, layoutNodeSize
+		}
+
+		fmt.Fprintf(bw, "\n <node id=\"%d\">\n", n.ID)
+		writeXMLElement(bw, "  ", "cost", formatXMLCost(n.Cost))
+		writeXMLElement(bw, "  ", "label", n.Label)
+		bw.WriteString("  <graphics>\n   <center>\n")
+		writeXMLElement(bw, "    ", "x", formatXMLFloat(gr.Center.X))
+		writeXMLElement(bw, "    ", "y", formatXMLFloat(gr.Center.Y))
+		writeXMLElement(bw, "    ", "z", formatXMLFloat(gr.Center.Z))
+		bw.WriteString("   </center>\n")
+		writeXMLElement(bw, "   ", "width", formatXMLFloat(gr.Width))
+		writeXMLElement(bw, "   ", "height", formatXMLFloat(gr.Height))
+		writeXMLElement(bw, "   ", "depth", formatXMLFloat(gr.Depth))
+		bw.WriteString("  </graphics>\n </node>\n")
+	}
+
+	for i := range g.Edges {
+		e := &g.Edges[i]
+		dir := "no"
+		if e.Directed {
+			dir = "yes"
+		}
+
+		fmt.Fprintf(bw, "\n <edge directed=\"%s\">\n", dir)
+		writeXMLElement(bw, "  ", "cost", formatXMLCost(e.Cost))
+		writeXMLElement(bw, "  ", "label", e.Label)
+		writeXMLElement(bw, "  ", "source", strconv.Itoa(e.Src))
+		writeXMLElement(bw, "  ", "target", strconv.Itoa(e.Dst))
+		bw.WriteString(" </edge>\n")
+	}
+
+	bw.WriteString("\n</graph>\n")
+
+	return bw.Flush()
+}
+
+// writeXMLElement writes an element holding the given text on its own line.
+// Elements without text are written as empty elements, like graph.jar does.
+func writeXMLElement(w *bufio.Writer, indent, name, text string) {
+	w.WriteString(indent)
+	if text == "" {
+		w.WriteString("<" + name + "/>\n")
+		return
+	}
+
+	w.WriteString("<" + name + ">")
+	xml.EscapeText(w, []byte(text))
+	w.WriteString("</" + name + ">\n")
+}
+
+// formatXMLCost formats costs like graph.jar, which always writes a decimal point.
+func formatXMLCost(v float64) string {
+	s := formatXMLFloat(v)
+	if !strings.ContainsAny(s, ".NI") {
+		s += ".0"
+	}
+	return s
+}
+
+func formatXMLFloat(v float64) string {
+	if v == 0 {
+		// Negative zero, such as a flipped y coordinate, is written as zero.
+		v = 0
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
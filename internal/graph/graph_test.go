@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"encoding/xml"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/tmaxmax/xml-to-graph/internal/graph"
@@ -11,12 +12,21 @@ import (
 
 var expected = graph.Graph{
 	Nodes: []graph.Node{
-		{ID: 1, Cost: 30.0},
-		{ID: 2, Cost: 20.0},
-		{ID: 3, Cost: 10.0},
+		{ID: 1, Cost: 30.0, Label: "Arad", Graphics: graph.Graphics{
+			Center: graph.Point{X: 302, Y: 194},
+			Width:  20, Height: 20, Depth: 20,
+		}},
+		{ID: 2, Cost: 20.0, Graphics: graph.Graphics{
+			Center: graph.Point{X: 187, Y: 350},
+			Width:  20, Height: 20, Depth: 20,
+		}},
+		{ID: 3, Cost: 10.0, Label: "Cluj", Graphics: graph.Graphics{
+			Center: graph.Point{X: 110, Y: 84},
+			Width:  20, Height: 20, Depth: 20,
+		}},
 	},
 	Edges: []graph.Edge{
-		{Src: 2, Dst: 1, Cost: 50.0},
+		{Src: 2, Dst: 1, Cost: 50.0, Label: "E1 & E2"},
 		{Src: 3, Dst: 2, Cost: 100.0, Directed: true},
 	},
 }
@@ -45,6 +55,314 @@ func TestFromXMLNoStd(t *testing.T) {
 	}
 }
 
+func TestFromXMLNoStdEntities(t *testing.T) {
+	in := `<graph>
+ <node id="1"><label>&lt;A&gt; &amp; &quot;B&quot; &apos;C&apos;</label></node>
+ <node id="2"><label>&#68;&#x45; &nbsp; &#xZZ; &amp</label></node>
+</graph>`
+
+	g, err := graph.FromXMLNoStd(bufio.NewReader(strings.NewReader(in)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	labels := []string{`<A> & "B" 'C'`, "DE &nbsp; &#xZZ; &amp"}
+	if len(g.Nodes) != len(labels) {
+		t.Fatalf("Expected %d nodes, received %+v", len(labels), g.Nodes)
+	}
+	for i, n := range g.Nodes {
+		if n.Label != labels[i] {
+			t.Fatalf("Invalid label:\nexpected %q\nreceived %q", labels[i], n.Label)
+		}
+	}
+}
+
+func TestFromXMLErrors(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"garbage",
+		`<?xml version="1.0"?><nodes></nodes>`,
+		`<graph><node id="1"></node>`,
+		`<graph><node id="1"><cost>1`,
+		`<nodes><node id="1"></node></nodes>`,
+	} {
+		if _, err := graph.FromXML(strings.NewReader(in)); err == nil {
+			t.Fatalf("FromXML: expected error for %q", in)
+		}
+		if _, err := graph.FromXMLNoStd(bufio.NewReader(strings.NewReader(in))); err == nil {
+			t.Fatalf("FromXMLNoStd: expected error for %q", in)
+		}
+	}
+
+	in := "<graph>\n</graph>\n"
+	if _, err := graph.FromXML(strings.NewReader(in)); err != nil {
+		t.Fatalf("FromXML: unexpected error for an empty graph: %v", err)
+	}
+	if _, err := graph.FromXMLNoStd(bufio.NewReader(strings.NewReader(in))); err != nil {
+		t.Fatalf("FromXMLNoStd: unexpected error for an empty graph: %v", err)
+	}
+}
+
+func TestToXML(t *testing.T) {
+	var sb strings.Builder
+	if err := graph.ToXML(&sb, &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := graph.FromXML(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(g, expected) {
+		t.Fatalf("Invalid output:\nexpected %+v\nreceived %+v", expected, g)
+	}
+}
+
+func TestToXMLLayout(t *testing.T) {
+	in := graph.Graph{
+		Nodes: []graph.Node{{ID: 1, Label: "<A & B>"}, {ID: 2}, {ID: 3}, {ID: 4}},
+		Edges: []graph.Edge{{Src: 1, Dst: 2, Cost: 1.5}},
+	}
+
+	var first, second strings.Builder
+	if err := graph.ToXML(&first, &in); err != nil {
+		t.Fatal(err)
+	}
+	_ = graph.ToXML(&second, &in)
+	if first.String() != second.String() {
+		t.Fatalf("Layout is not deterministic:\n%s\n%s", first.String(), second.String())
+	}
+
+	g, err := graph.FromXMLNoStd(bufio.NewReader(strings.NewReader(first.String())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Nodes[0].Label != in.Nodes[0].Label || g.Edges[0] != in.Edges[0] {
+		t.Fatalf("Invalid graph read back: %+v", g)
+	}
+
+	seen := map[graph.Point]bool{}
+	for _, n := range g.Nodes {
+		if n.Graphics.Width != 20 || n.Graphics.Center.X <= 0 || n.Graphics.Center.Y <= 0 || seen[n.Graphics.Center] {
+			t.Fatalf("Invalid layout for node %+v", n)
+		}
+		seen[n.Graphics.Center] = true
+	}
+}
+
+func TestToXMLKeepsLayout(t *testing.T) {
+	in := graph.Graph{
+		Nodes: []graph.Node{
+			{ID: 1, Graphics: graph.Graphics{Width: 20, Height: 20, Depth: 20}},
+			{ID: 2, Graphics: graph.Graphics{Center: graph.Point{X: 100, Y: 50}, Width: 20, Height: 20, Depth: 20}},
+		},
+	}
+
+	var sb strings.Builder
+	if err := graph.ToXML(&sb, &in); err != nil {
+		t.Fatal(err)
+	}
+	g, err := graph.FromXML(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, in) {
+		t.Fatalf("Layout was changed:\nexpected %+v\nreceived %+v", in, g)
+	}
+}
+
+func TestFromDOT(t *testing.T) {
+	in := `/* A drawing made in Graphviz. */
+strict digraph "test" {
+	# A preprocessor line.
+	rankdir = LR; // A graph attribute.
+	node [cost=2]
+	1 [label="Arad", pos="302,-194!"]
+	Cluj -> 1 [weight=5, label="E" + "1"]
+	edge [dir=none]
+	subgraph cluster { 1:n -> { 7 "Iasi" } [cost=1.5] }
+	Cluj -> 7 [dir=forward, cost=-1, weight=3];
+}
+`
+	g, err := graph.FromDOT(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := graph.Graph{
+		Nodes: []graph.Node{
+			{ID: 1, Cost: 2, Label: "Arad", Graphics: graph.Graphics{Center: graph.Point{X: 302, Y: 194}}},
+			{ID: 8, Cost: 2, Label: "Cluj"},
+			{ID: 7, Cost: 2},
+			{ID: 9, Cost: 2, Label: "Iasi"},
+		},
+		Edges: []graph.Edge{
+			{Src: 8, Dst: 1, Cost: 5, Label: "E1", Directed: true},
+			{Src: 1, Dst: 7, Cost: 1.5},
+			{Src: 1, Dst: 9, Cost: 1.5},
+			{Src: 8, Dst: 7, Cost: -1, Directed: true},
+		},
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Fatalf("Invalid output:\nexpected %+v\nreceived %+v", want, g)
+	}
+
+	var sb strings.Builder
+	if err := graph.ToDOT(&sb, &expected); err != nil {
+		t.Fatal(err)
+	}
+	if g, err = graph.FromDOT(strings.NewReader(sb.String())); err != nil {
+		t.Fatal(err)
+	}
+	// The sizes of the nodes are the only data DOT doesn't keep.
+	want = graph.Graph{Nodes: make([]graph.Node, len(expected.Nodes)), Edges: expected.Edges}
+	for i, n := range expected.Nodes {
+		n.Graphics.Width, n.Graphics.Height, n.Graphics.Depth = 0, 0, 0
+		want.Nodes[i] = n
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Fatalf("Invalid graph read from %q:\nexpected %+v\nreceived %+v", sb.String(), want, g)
+	}
+
+	escaped := graph.Graph{
+		Nodes: []graph.Node{{ID: 1, Label: `C:\Users\"Ana"`}, {ID: 2, Label: `\\`}},
+		Edges: []graph.Edge{{Src: 1, Dst: 2, Label: `a\nb`}},
+	}
+	sb.Reset()
+	if err := graph.ToDOT(&sb, &escaped); err != nil {
+		t.Fatal(err)
+	}
+	if g, err = graph.FromDOT(strings.NewReader(sb.String())); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, escaped) {
+		t.Fatalf("Invalid graph read from %q:\nexpected %+v\nreceived %+v", sb.String(), escaped, g)
+	}
+
+	for _, in := range []string{
+		"graph { 1 -> 2 }",
+		"digraph { 1 -- 2 }",
+		"graph { 1 -- 2 [cost=abc] }",
+		"graph { 1 -- \"2 }",
+		"graph { 1 -- 2 ",
+		"graph { } graph { }",
+		"tree { }",
+	} {
+		if _, err := graph.FromDOT(strings.NewReader(in)); err == nil {
+			t.Fatalf("Expected error when reading %q", in)
+		}
+	}
+}
+
+func TestGraphML(t *testing.T) {
+	var sb strings.Builder
+	if err := graph.ToGraphML(&sb, &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := graph.FromGraphML(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, expected) {
+		t.Fatalf("Invalid graph read from %q:\nexpected %+v\nreceived %+v", sb.String(), expected, g)
+	}
+
+	// Saved by yEd, with the labels and geometry in its graphics.
+	const yed = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns" xmlns:y="http://www.yworks.com/xml/graphml">
+  <key for="node" id="d0" yfiles.type="nodegraphics"/>
+  <key for="edge" id="d1" yfiles.type="edgegraphics"/>
+  <key attr.name="weight" attr.type="double" for="edge" id="d2"><default>1</default></key>
+  <graph edgedefault="directed" id="G">
+    <node id="n0">
+      <data key="d0"><y:ShapeNode><y:Geometry height="30.0" width="30.0" x="85.0" y="-15.0"/><y:NodeLabel>Arad &amp; Cluj</y:NodeLabel></y:ShapeNode></data>
+    </node>
+    <node id="n1">
+      <data key="d0"><y:ShapeNode><y:Geometry height="30.0" width="30.0" x="185.0" y="-15.0"/><y:NodeLabel/></y:ShapeNode></data>
+    </node>
+    <edge id="e0" source="n0" target="n1">
+      <data key="d1"><y:PolyLineEdge><y:EdgeLabel>E1</y:EdgeLabel></y:PolyLineEdge></data>
+    </edge>
+    <edge id="e1" source="n1" target="n0" directed="false">
+      <data key="d2">2.5</data>
+    </edge>
+  </graph>
+</graphml>`
+
+	if g, err = graph.FromGraphML(strings.NewReader(yed)); err != nil {
+		t.Fatal(err)
+	}
+	want := graph.Graph{
+		Nodes: []graph.Node{
+			{ID: 0, Label: "Arad & Cluj", Graphics: graph.Graphics{Center: graph.Point{X: 100}, Width: 30, Height: 30}},
+			{ID: 1, Label: "n1", Graphics: graph.Graphics{Center: graph.Point{X: 200}, Width: 30, Height: 30}},
+		},
+		Edges: []graph.Edge{
+			{Src: 0, Dst: 1, Cost: 1, Label: "E1", Directed: true},
+			{Src: 1, Dst: 0, Cost: 2.5},
+		},
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Fatalf("Invalid graph read from yEd:\nexpected %+v\nreceived %+v", want, g)
+	}
+
+	// Saved by Gephi, with integer node IDs.
+	const gephi = `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key attr.name="label" attr.type="string" for="node" id="label"/>
+  <key attr.name="Weight" attr.type="double" for="edge" id="weight"/>
+  <graph defaultedgetype="undirected" edgedefault="undirected">
+    <node id="4"><data key="label">Arad</data></node>
+    <node id="2"/>
+    <edge source="4" target="2"><data key="weight">3.0</data></edge>
+    <edge source="2" target="4" directed="1"/>
+    <edge source="2" target="2" directed="0"/>
+  </graph>
+</graphml>`
+
+	if g, err = graph.FromGraphML(strings.NewReader(gephi)); err != nil {
+		t.Fatal(err)
+	}
+	want = graph.Graph{
+		Nodes: []graph.Node{{ID: 4, Label: "Arad"}, {ID: 2}},
+		Edges: []graph.Edge{{Src: 4, Dst: 2, Cost: 3}, {Src: 2, Dst: 4, Directed: true}, {Src: 2, Dst: 2}},
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Fatalf("Invalid graph read from Gephi:\nexpected %+v\nreceived %+v", want, g)
+	}
+
+	// Keys with the same name, the defaults of the last one being used.
+	const duplicateKeys = `<graphml>
+  <key id="w0" for="edge" attr.name="weight"><default>1</default></key>
+  <key id="w1" for="edge" attr.name="Weight"><default>2</default></key>
+  <graph><node id="1"/><edge source="1" target="1"/></graph>
+</graphml>`
+
+	for i := 0; i < 20; i++ {
+		if g, err = graph.FromGraphML(strings.NewReader(duplicateKeys)); err != nil {
+			t.Fatal(err)
+		}
+		if g.Edges[0].Cost != 2 {
+			t.Fatalf("Invalid cost for duplicate keys: %v", g.Edges[0].Cost)
+		}
+	}
+
+	for _, in := range []string{
+		`<graphml><graph><node id="1"/><edge source="1" target="2"/></graph></graphml>`,
+		`<graphml><graph><node id="1"/><node id="1"/></graph></graphml>`,
+		`<graphml><graph edgedefault="both"/></graphml>`,
+		`<graphml><graph><node id="1"/><edge source="1" target="1" directed="yes"/></graph></graphml>`,
+		`<graphml><key id="w" attr.name="weight"/><graph><node id="1"/><edge source="1" target="1"><data key="w">abc</data></edge></graph></graphml>`,
+		`<graph></graph>`,
+		`<graphml><graph>`,
+	} {
+		if _, err := graph.FromGraphML(strings.NewReader(in)); err == nil {
+			t.Fatalf("Expected error when reading %q", in)
+		}
+	}
+}
+
 func BenchmarkGraphUnmarshalXML_reflect(b *testing.B) {
 	b.ReportAllocs()
 
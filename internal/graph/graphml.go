@@ -0,0 +1,432 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type graphmlKey struct {
+	ID      string `xml:"id,attr"`
+	For     string `xml:"for,attr"`
+	Name    string `xml:"attr.name,attr"`
+	YFiles  string `xml:"yfiles.type,attr"`
+	Default string `xml:"default"`
+}
+
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+type graphmlNode struct {
+	ID    string        `xml:"id,attr"`
+	Data  []graphmlData `xml:"data"`
+	Graph *graphmlGraph `xml:"graph"`
+}
+
+type graphmlEdge struct {
+	Source   string        `xml:"source,attr"`
+	Target   string        `xml:"target,attr"`
+	Directed string        `xml:"directed,attr"`
+	Data     []graphmlData `xml:"data"`
+}
+
+type graphmlGraph struct {
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphmlNode `xml:"node"`
+	Edges       []graphmlEdge `xml:"edge"`
+}
+
+type graphmlDocument struct {
+	XMLName xml.Name       `xml:"graphml"`
+	Keys    []graphmlKey   `xml:"key"`
+	Graphs  []graphmlGraph `xml:"graph"`
+}
+
+// graphmlReader gathers the nodes and edges of the graphs in a document,
+// nested graphs included.
+type graphmlReader struct {
+	// keys holds the keys in the order they are declared, and byID their positions.
+	keys  []graphmlKey
+	byID  map[string]int
+	index map[string]int
+	nodes []*graphmlNode
+	edges []*graphmlEdge
+	// directed holds whether each edge is directed.
+	directed []bool
+}
+
+// FromGraphML reads a graph from a GraphML file, such as the ones saved by
+// yEd and Gephi. The nodes and edges of all the graphs in the file, including
+// the ones nested in nodes, are read into a single graph; hyperedges are ignored.
+//
+// Node IDs are handled like FromDOT handles node names: IDs which are
+// non-negative integers are kept, the other nodes are given the next unused
+// IDs and their GraphML IDs become their labels. The data of the keys named
+// "label" become labels, the ones of the keys named "cost", or "weight" if
+// there are none, costs, the ones of the keys named "x", "y" and "z" the
+// centers of the nodes, and the ones of the keys named "width", "height" and
+// "depth" their sizes. The labels and geometry of the graphics of yEd
+// are read as well. Edges are directed as given by their "directed" attribute
+// or, if they don't have one, by the "edgedefault" attribute of their graph,
+// which is "directed" if it is missing.
+func FromGraphML(r io.Reader) (Graph, error) {
+	var doc graphmlDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return Graph{}, fmt.Errorf("graphml: %w", err)
+	}
+
+	gr := &graphmlReader{keys: doc.Keys, byID: map[string]int{}, index: map[string]int{}}
+	for i, k := range doc.Keys {
+		gr.byID[k.ID] = i
+	}
+	for i := range doc.Graphs {
+		if err := gr.collect(&doc.Graphs[i]); err != nil {
+			return Graph{}, err
+		}
+	}
+
+	return gr.graph()
+}
+
+func (gr *graphmlReader) collect(g *graphmlGraph) error {
+	var defaultDirected bool
+	switch g.EdgeDefault {
+	case "", "directed":
+		defaultDirected = true
+	case "undirected":
+	default:
+		return fmt.Errorf("graphml: invalid edgedefault %q", g.EdgeDefault)
+	}
+
+	for i := range g.Nodes {
+		n := &g.Nodes[i]
+		if _, ok := gr.index[n.ID]; ok {
+			return fmt.Errorf("graphml: duplicate node %q", n.ID)
+		}
+
+		gr.index[n.ID] = len(gr.nodes)
+		gr.nodes = append(gr.nodes, n)
+
+		if n.Graph != nil {
+			if err := gr.collect(n.Graph); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := range g.Edges {
+		e := &g.Edges[i]
+		dir := defaultDirected
+		switch e.Directed {
+		case "":
+		case "true", "1":
+			dir = true
+		case "false", "0":
+			dir = false
+		default:
+			return fmt.Errorf("graphml: edge %q -> %q: invalid directed value %q", e.Source, e.Target, e.Directed)
+		}
+
+		gr.edges = append(gr.edges, e)
+		gr.directed = append(gr.directed, dir)
+	}
+
+	return nil
+}
+
+func (gr *graphmlReader) graph() (Graph, error) {
+	g := Graph{Nodes: make([]Node, len(gr.nodes)), Edges: make([]Edge, len(gr.edges))}
+
+	names := make([]string, len(gr.nodes))
+	for i, n := range gr.nodes {
+		names[i] = n.ID
+	}
+	ids, named := idsFromNames(names)
+
+	for i, n := range gr.nodes {
+		nd := &g.Nodes[i]
+		nd.ID = ids[i]
+		if !named[i] {
+			nd.Label = n.ID
+		}
+
+		attrs, yLabel, err := gr.attrs("node", n.Data, &nd.Graphics)
+		if err == nil {
+			err = graphmlApply(attrs, yLabel, &nd.Label, &nd.Cost)
+		}
+		if err == nil {
+			err = graphmlGraphics(attrs, &nd.Graphics)
+		}
+		if err != nil {
+			return Graph{}, fmt.Errorf("graphml: node %q: %w", n.ID, err)
+		}
+	}
+
+	for i, e := range gr.edges {
+		src, ok := gr.index[e.Source]
+		if !ok {
+			return Graph{}, fmt.Errorf("graphml: edge %q -> %q: unknown node %q", e.Source, e.Target, e.Source)
+		}
+		dst, ok := gr.index[e.Target]
+		if !ok {
+			return Graph{}, fmt.Errorf("graphml: edge %q -> %q: unknown node %q", e.Source, e.Target, e.Target)
+		}
+
+		ge := &g.Edges[i]
+		ge.Src, ge.Dst = ids[src], ids[dst]
+		ge.Directed = directed(gr.directed[i])
+
+		attrs, yLabel, err := gr.attrs("edge", e.Data, nil)
+		if err == nil {
+			err = graphmlApply(attrs, yLabel, &ge.Label, &ge.Cost)
+		}
+		if err != nil {
+			return Graph{}, fmt.Errorf("graphml: edge %q -> %q: %w", e.Source, e.Target, err)
+		}
+	}
+
+	return g, nil
+}
+
+// attrs returns the values of the keys of the given domain, by their lowercase
+// names, with the defaults of the keys which have no data. Of the defaults of
+// the keys with the same name, the one of the key declared last is used. The
+// label of the yEd graphics, if there are any, is returned separately, and their
+// geometry is read into gr, when it is not nil.
+func (gr *graphmlReader) attrs(domain string, data []graphmlData, graphics *Graphics) (map[string]string, string, error) {
+	attrs := map[string]string{}
+	for _, k := range gr.keys {
+		if k.Default != "" && graphmlKeyFor(k, domain) {
+			attrs[graphmlKeyName(k)] = k.Default
+		}
+	}
+
+	var yLabel string
+	for _, d := range data {
+		k := graphmlKey{ID: d.Key}
+		if i, ok := gr.byID[d.Key]; ok {
+			k = gr.keys[i]
+		}
+		if !graphmlKeyFor(k, domain) {
+			continue
+		}
+
+		if k.YFiles == domain+"graphics" {
+			label, err := yedGraphics(d.Inner, graphics)
+			if err != nil {
+				return nil, "", err
+			}
+			if yLabel == "" {
+				yLabel = label
+			}
+			continue
+		}
+
+		attrs[graphmlKeyName(k)] = d.Text
+	}
+
+	return attrs, yLabel, nil
+}
+
+func graphmlKeyFor(k graphmlKey, domain string) bool {
+	return k.For == "" || k.For == "all" || k.For == domain
+}
+
+// graphmlKeyName returns the lowercase name of the key, or its ID if it has no name.
+func graphmlKeyName(k graphmlKey) string {
+	if k.Name == "" {
+		return strings.ToLower(k.ID)
+	}
+	return strings.ToLower(k.Name)
+}
+
+// graphmlApply sets the label and the cost of a node or an edge from its attributes.
+// The label of the yEd graphics is used if there is no "label" key.
+func graphmlApply(attrs map[string]string, yLabel string, label *string, cost *float64) error {
+	if l, ok := attrs["label"]; ok {
+		*label = l
+	} else if yLabel != "" {
+		*label = yLabel
+	}
+	return attrCost(attrs, cost)
+}
+
+// graphmlGraphics sets the center and the size of a node from its attributes.
+func graphmlGraphics(attrs map[string]string, gr *Graphics) error {
+	for _, c := range []struct {
+		name string
+		v    *float64
+	}{
+		{"x", &gr.Center.X}, {"y", &gr.Center.Y}, {"z", &gr.Center.Z},
+		{"width", &gr.Width}, {"height", &gr.Height}, {"depth", &gr.Depth},
+	} {
+		v, ok := attrs[c.name]
+		if !ok {
+			continue
+		}
+
+		var err error
+		if *c.v, err = strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil {
+			return fmt.Errorf("invalid %s %q", c.name, v)
+		}
+	}
+	return nil
+}
+
+// yedGraphics reads the graphics yEd saves for nodes and edges, returning the
+// text of the first label. The geometry of a node gives its center and its size.
+func yedGraphics(inner string, gr *Graphics) (string, error) {
+	dec := xml.NewDecoder(strings.NewReader(inner))
+
+	var label strings.Builder
+	var inLabel, hasLabel bool
+	for {
+		it, err := dec.RawToken()
+		if err == io.EOF {
+			return label.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch t := it.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "NodeLabel", "EdgeLabel":
+				inLabel = !hasLabel
+			case "Geometry":
+				if gr != nil {
+					if err := yedGeometry(t.Attr, gr); err != nil {
+						return "", err
+					}
+				}
+			}
+		case xml.EndElement:
+			if inLabel && (t.Name.Local == "NodeLabel" || t.Name.Local == "EdgeLabel") {
+				inLabel, hasLabel = false, true
+			}
+		case xml.CharData:
+			if inLabel {
+				label.Write(t)
+			}
+		}
+	}
+}
+
+// yedGeometry reads the position and size of a node from the attributes of
+// a yEd geometry, where the position is the one of the top left corner.
+func yedGeometry(attrs []xml.Attr, gr *Graphics) error {
+	var x, y, w, h float64
+	for _, a := range []struct {
+		name string
+		v    *float64
+	}{{"x", &x}, {"y", &y}, {"width", &w}, {"height", &h}} {
+		attr := findAttribute(attrs, a.name)
+		if attr == nil {
+			continue
+		}
+
+		var err error
+		if *a.v, err = strconv.ParseFloat(attr.Value, 64); err != nil {
+			return fmt.Errorf("invalid geometry %s %q", a.name, attr.Value)
+		}
+	}
+
+	gr.Center.X, gr.Center.Y = x+w/2, y+h/2
+	gr.Width, gr.Height = w, h
+	return nil
+}
+
+// graphmlHeader opens a GraphML document and declares the keys written by ToGraphML.
+const graphmlHeader = `<graphml xmlns="http://graphml.graphdrawing.org/xmlns" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://graphml.graphdrawing.org/xmlns http://graphml.graphdrawing.org/xmlns/1.0/graphml.xsd">
+  <key id="label" for="node" attr.name="label" attr.type="string"/>
+  <key id="cost" for="node" attr.name="cost" attr.type="double"/>
+  <key id="x" for="node" attr.name="x" attr.type="double"/>
+  <key id="y" for="node" attr.name="y" attr.type="double"/>
+  <key id="z" for="node" attr.name="z" attr.type="double"/>
+  <key id="width" for="node" attr.name="width" attr.type="double"/>
+  <key id="height" for="node" attr.name="height" attr.type="double"/>
+  <key id="depth" for="node" attr.name="depth" attr.type="double"/>
+  <key id="edge-label" for="edge" attr.name="label" attr.type="string"/>
+  <key id="weight" for="edge" attr.name="weight" attr.type="double"/>
+`
+
+// ToGraphML writes the graph in the GraphML format, which FromGraphML reads.
+// Labels and costs are written as the "label" and "cost" keys of the nodes and
+// the "label" and "weight" keys of the edges, the centers of the nodes, if any
+// node has a position, as the "x", "y" and "z" keys, and the sizes of the nodes
+// which have one as the "width", "height" and "depth" keys. The default direction
+// of the edges is the one of most of them, and the other edges have a "directed"
+// attribute.
+func ToGraphML(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+
+	var directedEdges int
+	for i := range g.Edges {
+		if g.Edges[i].Directed {
+			directedEdges++
+		}
+	}
+	defaultDirected := directedEdges*2 > len(g.Edges)
+	edgeDefault := "undirected"
+	if defaultDirected {
+		edgeDefault = "directed"
+	}
+
+	placed := g.hasLayout()
+
+	bw.WriteString(xml.Header)
+	bw.WriteString(graphmlHeader)
+	fmt.Fprintf(bw, "  <graph id=\"G\" edgedefault=\"%s\">\n", edgeDefault)
+
+	for i := range g.Nodes {
+		n := &g.Nodes[i]
+		fmt.Fprintf(bw, "    <node id=\"%d\">\n", n.ID)
+		if n.Label != "" {
+			writeGraphMLData(bw, "label", n.Label)
+		}
+		writeGraphMLData(bw, "cost", formatXMLFloat(n.Cost))
+		if placed {
+			c := n.Graphics.Center
+			writeGraphMLData(bw, "x", formatXMLFloat(c.X))
+			writeGraphMLData(bw, "y", formatXMLFloat(c.Y))
+			writeGraphMLData(bw, "z", formatXMLFloat(c.Z))
+		}
+		if gr := n.Graphics; gr.Width != 0 || gr.Height != 0 || gr.Depth != 0 {
+			writeGraphMLData(bw, "width", formatXMLFloat(gr.Width))
+			writeGraphMLData(bw, "height", formatXMLFloat(gr.Height))
+			writeGraphMLData(bw, "depth", formatXMLFloat(gr.Depth))
+		}
+		bw.WriteString("    </node>\n")
+	}
+
+	for i := range g.Edges {
+		e := &g.Edges[i]
+		fmt.Fprintf(bw, "    <edge source=\"%d\" target=\"%d\"", e.Src, e.Dst)
+		if bool(e.Directed) != defaultDirected {
+			fmt.Fprintf(bw, " directed=\"%t\"", bool(e.Directed))
+		}
+		bw.WriteString(">\n")
+		if e.Label != "" {
+			writeGraphMLData(bw, "edge-label", e.Label)
+		}
+		writeGraphMLData(bw, "weight", formatXMLFloat(e.Cost))
+		bw.WriteString("    </edge>\n")
+	}
+
+	bw.WriteString("  </graph>\n</graphml>\n")
+
+	return bw.Flush()
+}
+
+func writeGraphMLData(w *bufio.Writer, key, text string) {
+	fmt.Fprintf(w, "      <data key=\"%s\">", key)
+	xml.EscapeText(w, []byte(text))
+	w.WriteString("</data>\n")
+}
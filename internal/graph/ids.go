@@ -0,0 +1,146 @@
+package graph
+
+import (
+	"sort"
+	"strconv"
+)
+
+// idIndex maps node IDs to their position in the sorted list of
+// the distinct node IDs of a graph.
+type idIndex struct {
+	// offset is used instead of m when the IDs are contiguous:
+	// the position of an ID is then ID-offset.
+	offset int
+	m      map[int]int
+	// ids holds the distinct IDs, sorted, when m is used.
+	ids []int
+	n   int
+}
+
+func newIDIndex(nodes []Node) *idIndex {
+	if len(nodes) == 0 {
+		return &idIndex{}
+	}
+
+	contiguous := true
+	for i := 1; i < len(nodes) && contiguous; i++ {
+		contiguous = nodes[i].ID == nodes[i-1].ID+1
+	}
+
+	if contiguous {
+		return &idIndex{offset: nodes[0].ID, n: len(nodes)}
+	}
+
+	ids := make([]int, len(nodes))
+	for i := range nodes {
+		ids[i] = nodes[i].ID
+	}
+	sort.Ints(ids)
+
+	x := &idIndex{m: make(map[int]int, len(ids))}
+	for i, id := range ids {
+		if i > 0 && id == ids[i-1] {
+			continue
+		}
+		x.m[id] = x.n
+		ids[x.n] = id
+		x.n++
+	}
+	x.ids = ids[:x.n]
+
+	return x
+}
+
+// position returns the position of the given ID and whether
+// a node with this ID exists.
+func (x *idIndex) position(id int) (int, bool) {
+	if x.m == nil {
+		p := id - x.offset
+		return p, p >= 0 && p < x.n
+	}
+
+	p, ok := x.m[id]
+	return p, ok
+}
+
+// danglingIndex maps the IDs referenced by edges which don't belong to any node
+// to their position in the sorted list of these IDs.
+func danglingIndex(x *idIndex, edges []Edge) map[int]int {
+	var ids []int
+	seen := map[int]bool{}
+	for i := range edges {
+		for _, id := range [2]int{edges[i].Src, edges[i].Dst} {
+			if _, ok := x.position(id); !ok && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Ints(ids)
+
+	m := make(map[int]int, len(ids))
+	for i, id := range ids {
+		m[id] = i
+	}
+
+	return m
+}
+
+// id returns the ID at the given position.
+func (x *idIndex) id(position int) int {
+	if x.m == nil {
+		return x.offset + position
+	}
+	return x.ids[position]
+}
+
+// IDBase makes the Printer compact node IDs to the range [base, base+n),
+// where n is the number of distinct node IDs. The relative order of the
+// IDs is kept, so a graph with the nodes 1, 2, 4, 5 is printed with the
+// nodes 1, 2, 3, 4 when the base is 1. IDs referenced by edges which don't
+// belong to any node are numbered after the nodes, so they never collide
+// with the IDs of existing nodes.
+//
+// By default node IDs are printed as they are in the graph.
+func IDBase(base int) PrinterOption {
+	return func(p *Printer) {
+		p.idBase = base
+		p.compactIDs = true
+	}
+}
+
+// PreserveIDs makes the Printer write node IDs as they are in the graph,
+// even if IDBase is given too. Verbs that rely on node positions, such as
+// the adjacency matrix, still use the compacted IDs internally, so their
+// output remains correct.
+func PreserveIDs() PrinterOption {
+	return func(p *Printer) {
+		p.preserveIDs = true
+	}
+}
+
+// idsFromNames gives IDs to the nodes with the given names. Nodes named by
+// non-negative integers keep them as IDs, and are reported as named. The
+// others are given the next unused IDs, in the order they appear.
+func idsFromNames(names []string) (ids []int, named []bool) {
+	ids, named = make([]int, len(names)), make([]bool, len(names))
+
+	next := 0
+	for i, name := range names {
+		if id, err := strconv.Atoi(name); err == nil && id >= 0 && strconv.Itoa(id) == name {
+			ids[i], named[i] = id, true
+			if id >= next {
+				next = id + 1
+			}
+		}
+	}
+
+	for i := range names {
+		if !named[i] {
+			ids[i] = next
+			next++
+		}
+	}
+
+	return ids, named
+}
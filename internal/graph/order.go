@@ -0,0 +1,128 @@
+package graph
+
+import "sort"
+
+// The orders in which the N and M verbs can print the nodes and edges.
+const (
+	// orderID sorts nodes by ID and edges by source, then by target.
+	orderID = "id"
+	// orderLabel sorts nodes by label and edges by the label of the source,
+	// then by the label of the target.
+	orderLabel = "label"
+	// orderCost sorts by cost, ascending.
+	orderCost = "cost"
+	// orderCostDesc sorts by cost, descending.
+	orderCostDesc = "cost-desc"
+)
+
+func validOrder(order string) bool {
+	switch order {
+	case orderID, orderLabel, orderCost, orderCostDesc:
+		return true
+	default:
+		return false
+	}
+}
+
+// NormalizeUndirected makes the Printer write the endpoints of undirected
+// edges in ascending order of their IDs, so that the source of such an edge
+// is always smaller than its target. Directed edges are not changed.
+func NormalizeUndirected() PrinterOption {
+	return func(p *Printer) {
+		p.normalizeUndirected = true
+	}
+}
+
+// name returns the label of the node with the given ID as it is printed.
+func (s *state) name(id int) string {
+	return string(s.appendName(nil, id, true))
+}
+
+// orderedNodes returns the nodes of the graph in the given order.
+// Nodes which compare equal keep their order in the graph.
+func (s *state) orderedNodes(order string) []Node {
+	if order == "" {
+		return s.Nodes
+	}
+
+	nodes := append([]Node(nil), s.Nodes...)
+
+	switch order {
+	case orderID:
+		sort.SliceStable(nodes, func(i, j int) bool {
+			return s.id(nodes[i].ID) < s.id(nodes[j].ID)
+		})
+	case orderLabel:
+		names := make([]string, len(nodes))
+		for i := range nodes {
+			names[i] = string(s.appendLabel(nil, nodes[i].ID, nodes[i].Label))
+		}
+		sort.Stable(byName{names: names, swap: func(i, j int) { nodes[i], nodes[j] = nodes[j], nodes[i] }})
+	case orderCost:
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Cost < nodes[j].Cost })
+	case orderCostDesc:
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Cost > nodes[j].Cost })
+	}
+
+	return nodes
+}
+
+// orderedEdges returns the edges of the graph in the given order, with the
+// endpoints of the undirected edges normalized if the printer requires it.
+// Edges which compare equal keep their order in the graph.
+func (s *state) orderedEdges(order string) []Edge {
+	if order == "" && !s.p.normalizeUndirected {
+		return s.Edges
+	}
+
+	edges := append([]Edge(nil), s.Edges...)
+
+	if s.p.normalizeUndirected {
+		for i := range edges {
+			e := &edges[i]
+			if !e.Directed && s.id(e.Src) > s.id(e.Dst) {
+				e.Src, e.Dst = e.Dst, e.Src
+			}
+		}
+	}
+
+	switch order {
+	case orderID:
+		sort.SliceStable(edges, func(i, j int) bool {
+			a, b := &edges[i], &edges[j]
+			if a.Src != b.Src {
+				return s.id(a.Src) < s.id(b.Src)
+			}
+			return s.id(a.Dst) < s.id(b.Dst)
+		})
+	case orderLabel:
+		names := make([]string, len(edges))
+		for i := range edges {
+			// The labels are separated by a byte which can't be part of
+			// a label, so that the sources are compared first.
+			names[i] = s.name(edges[i].Src) + "\x00" + s.name(edges[i].Dst)
+		}
+		sort.Stable(byName{names: names, swap: func(i, j int) { edges[i], edges[j] = edges[j], edges[i] }})
+	case orderCost:
+		sort.SliceStable(edges, func(i, j int) bool { return edges[i].Cost < edges[j].Cost })
+	case orderCostDesc:
+		sort.SliceStable(edges, func(i, j int) bool { return edges[i].Cost > edges[j].Cost })
+	}
+
+	return edges
+}
+
+// byName sorts a slice by the names computed for its elements,
+// keeping the names in sync with the elements.
+type byName struct {
+	names []string
+	swap  func(i, j int)
+}
+
+func (b byName) Len() int           { return len(b.names) }
+func (b byName) Less(i, j int) bool { return b.names[i] < b.names[j] }
+
+func (b byName) Swap(i, j int) {
+	b.names[i], b.names[j] = b.names[j], b.names[i]
+	b.swap(i, j)
+}
@@ -0,0 +1,36 @@
+package graph
+
+import "sort"
+
+// presets are format strings for the common layouts of graph exercise input files.
+var presets = map[string]string{
+	// The number of nodes and edges, followed by each edge on its own line.
+	"edges": "%n %m\n%M\n",
+	// The number of nodes and edges, followed by each edge and its cost on its own line.
+	"weighted-edges": "%n %m\n%1M\n",
+	// The number of nodes, followed by the adjacency matrix.
+	"matrix": "%n\n%a\n",
+	// The number of nodes, followed by the number of neighbours and the neighbours
+	// of each node, each node on its own line.
+	"adjacency-list": "%n\n%+L\n",
+	// The number of nodes, followed by the cost of each node.
+	"costs": "%n\n%w\n",
+}
+
+// Preset returns the format string of the preset with the given name,
+// and whether such a preset exists. See PresetNames for the available presets.
+func Preset(name string) (string, bool) {
+	f, ok := presets[name]
+	return f, ok
+}
+
+// PresetNames returns the names of the available presets, sorted.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
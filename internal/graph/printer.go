@@ -6,6 +6,7 @@ import (
 	"io"
 	"math"
 	"math/big"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -33,13 +34,69 @@ func flush(w writer) error {
 }
 
 type operation interface {
-	apply(writer, *Graph) (int, error)
+	apply(writer, *state) (int, error)
 }
 
-type operationFunc func(writer, *Graph) (int, error)
+// state holds the graph being printed and data derived from it
+// which is shared by the operations of a single Print call.
+type state struct {
+	*Graph
+	p        *Printer
+	labels   map[int]string
+	index    *idIndex
+	dangling map[int]int
+}
+
+// ids returns the index of the graph's node IDs.
+func (s *state) ids() *idIndex {
+	if s.index == nil {
+		s.index = newIDIndex(s.Nodes)
+	}
+	return s.index
+}
 
-func (o operationFunc) apply(w writer, g *Graph) (int, error) {
-	return o(w, g)
+// id returns the ID to be printed for the node with the given ID.
+func (s *state) id(id int) int {
+	if s.p.preserveIDs || !s.p.compactIDs {
+		return id
+	}
+	if p, ok := s.ids().position(id); ok {
+		return s.p.idBase + p
+	}
+	if s.dangling == nil {
+		s.dangling = danglingIndex(s.ids(), s.Edges)
+	}
+	return s.p.idBase + s.ids().n + s.dangling[id]
+}
+
+// appendName appends the name of the node with the given ID to b:
+// its label if labels is true and the node has one, otherwise its ID.
+func (s *state) appendName(b []byte, id int, labels bool) []byte {
+	if labels {
+		if s.labels == nil {
+			s.labels = make(map[int]string, len(s.Nodes))
+			for i := range s.Nodes {
+				s.labels[s.Nodes[i].ID] = s.Nodes[i].Label
+			}
+		}
+
+		return s.appendLabel(b, id, s.labels[id])
+	}
+
+	return strconv.AppendInt(b, int64(s.id(id)), 10)
+}
+
+// appendLabel appends the given label of the node with the given ID to b,
+// or the label fallback if the label is empty.
+func (s *state) appendLabel(b []byte, id int, label string) []byte {
+	if label != "" {
+		return append(b, label...)
+	}
+	if s.p.hasLabelFallback {
+		return append(b, s.p.labelFallback...)
+	}
+
+	return strconv.AppendInt(b, int64(s.id(id)), 10)
 }
 
 // ParsePrinterError is the type of error returned by ParseError
@@ -83,6 +140,54 @@ type Printer struct {
 	ops []operation
 	bwp sync.Pool
 	amp sync.Pool
+
+	labelFallback    string
+	hasLabelFallback bool
+	idBase           int
+	compactIDs       bool
+	preserveIDs      bool
+	escapes          bool
+	crlf             bool
+
+	normalizeUndirected bool
+}
+
+// A PrinterOption configures a Printer created by ParsePrinter.
+type PrinterOption func(*Printer)
+
+// Escapes makes ParsePrinter interpret the backslash escape sequences
+// "\n", "\r", "\t" and "\\" in the format string. This is useful when
+// the format string does not come from a shell which handles them.
+func Escapes() PrinterOption {
+	return func(p *Printer) {
+		p.escapes = true
+	}
+}
+
+// CRLF makes the printer end lines with "\r\n" instead of "\n". Line feeds
+// in the format string which aren't already preceded by a carriage return
+// are replaced, and the rows printed by verbs are separated by "\r\n".
+func CRLF() PrinterOption {
+	return func(p *Printer) {
+		p.crlf = true
+	}
+}
+
+// newline returns the line terminator used by the printer.
+func (p *Printer) newline() string {
+	if p.crlf {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// LabelFallback sets the text printed in place of a node's label
+// when the node has no label. By default the node's ID is printed instead.
+func LabelFallback(text string) PrinterOption {
+	return func(p *Printer) {
+		p.labelFallback = text
+		p.hasLabelFallback = true
+	}
 }
 
 // Print writes the given graph to the given writer.
@@ -107,8 +212,10 @@ func (p *Printer) Print(w io.Writer, g *Graph) (int, error) {
 		wr = bw
 	}
 
+	s := &state{Graph: g, p: p}
+
 	for _, op := range p.ops {
-		m, err := op.apply(wr, g)
+		m, err := op.apply(wr, s)
 		n += m
 		if err != nil {
 			return n, err
@@ -121,31 +228,98 @@ func (p *Printer) Print(w io.Writer, g *Graph) (int, error) {
 // ParsePrinter creates a printer from the given format string.
 //
 // The format strings are C-like (prefixed with %) and have the following verbs:
-//  - %: print a literal "%"
-//  - n: print the number of vertices a graph has
-//  - m: print the number of edges a graph has
-//  - {cost function}w: print the cost/weight of each vertex
-//  - {cost function}N: print each vertex, optionally together with its cost
-//  - {cost function}M: print each edge, optionally together with its cost
+//   - %: print a literal "%"
+//   - n: print the number of vertices a graph has
+//   - m: print the number of edges a graph has
+//   - {cost function}w: print the cost/weight of each vertex
+//   - {cost function}N: print each vertex, optionally together with its cost
+//   - {cost function}M: print each edge, optionally together with its cost
+//   - {cost function}L: print the adjacency list of each vertex on its own line,
+//     as "vertex: neighbour1 neighbour2 ...", optionally with the cost of each edge
+//     after each neighbour. Only the target of a directed edge is a neighbour of
+//     its source.
+//   - {cost function}W: print the cost matrix, where each cell holds the cost of
+//     the edge between the vertices, or the smallest one if there are more such edges
+//   - I: print the incidence matrix, with a row for each vertex and a column for each
+//     edge. Both vertices of an undirected edge are marked with 1, while the source
+//     of a directed edge is marked with 1 and its target with -1.
+//   - d: print the degree of each vertex
+//   - i: print the in-degree of each vertex. Undirected edges are both
+//     incoming and outgoing edges of their vertices.
+//   - o: print the out-degree of each vertex
+//   - {cost function}G: print the graph in the DOT language of Graphviz. Labels
+//     and costs are used as labels, and the positions from graph.jar are pinned.
+//
+// Some verbs accept named arguments, written in square brackets right after
+// the "%" sign, such as "%[none=INF,diag=0]W". Values containing commas or
+// closing brackets must be quoted: "%[none=\"-1, \"]W". The W verb accepts
+// the following arguments:
+//   - none: the text printed for missing edges (default "0")
+//   - diag: the text printed on the diagonal for missing edges (default is none)
+//
+// The I verb accepts the following arguments:
+//   - out: the text printed for the source of a directed edge (default "1")
+//   - in: the text printed for the target of a directed edge (default "-1")
+//
+// The d, i and o verbs accept the "sort" argument, which sorts the degrees
+// in ascending ("asc") or descending ("desc") order, such as "%[sort=desc]d".
+//
+// All verbs printing more values accept the "sep" argument, which sets the text
+// printed between the values on the same row (default " "). The N, M, L, a, W
+// and I verbs also accept the "row" argument, which sets the text printed between
+// rows (default is a line terminator). For example, "%[sep=\",\"]M" prints each
+// edge as "source,target" and "%[row=\" \"]M" prints all edges on a single line.
+//
+// Node IDs are printed as they are in the graph by all verbs. Use the IDBase
+// option to compact them to a dense range.
+//
+// The N, M and L verbs accept the "#" flag, placed right after the "%" sign,
+// which makes them print node labels instead of node IDs. Nodes without
+// a label are printed using their ID, unless the LabelFallback option
+// is given. For example, "%#M" prints each edge as "source-label target-label".
+//
+// The L verb also accepts the "+" flag, which replaces the "vertex:" prefix
+// of each line with the number of neighbours: "%+L" prints each line as
+// "degree neighbour1 neighbour2 ...".
+//
+// The N and M verbs accept the "sort" argument, which changes the order in which
+// the nodes and edges are printed. By default they are printed in the order they
+// appear in the graph. The following orders are available:
+//   - id: nodes by ID, edges by source, then by target
+//   - label: nodes by label, edges by the label of the source, then of the target
+//   - cost: by cost, ascending
+//   - cost-desc: by cost, descending
+//
+// Nodes and edges which compare equal keep their order. Use the NormalizeUndirected
+// option to print the smaller endpoint of undirected edges first.
 //
 // A cost function returns a new cost based on the actual one. It is useful for
 // adapting the output to your needs: for example, round the cost to the nearest
 // integer. A cost function is defined as following:
-//    {ratio}{rounding mode}
+//
+//	{ratio}{rounding mode}{number format}
+//
 // The ratio is a factor with which the cost is multiplied consisting of
 // ASCII digits and optionally the dot (".") character. For example, the
 // following are valid ratios:
-//  - .5: ratio of 0.5
-//  - 10: ratio of 10
-//  - 0.6: ratio of 0.6
+//   - .5: ratio of 0.5
+//   - 10: ratio of 10
+//   - 0.6: ratio of 0.6
+//
 // The rounding mode is optional. It can have the following values:
-//  - X (default): no rounding
-//  - F: flooring function
-//  - C: ceiling function
-//  - R: rounding to nearest integer function
+//   - X (default): no rounding
+//   - F: flooring function
+//   - C: ceiling function
+//   - R: rounding to nearest integer function
+//
+// The number format is optional too. It can have the following values:
+//   - none (default): the shortest decimal representation of the cost
+//   - P{decimals}: exactly this many decimals, for example "P2" prints 12.5 as 12.50
+//   - D: an integer, rounded to the nearest one unless a rounding mode is given
+//
 // Where a cost function is required for a verb, but none is provided,
-// the identity cost function is used (ratio 1, no rounding).
-func ParsePrinter(format string) (*Printer, error) {
+// the identity cost function is used (ratio 1, no rounding, shortest representation).
+func ParsePrinter(format string, opts ...PrinterOption) (*Printer, error) {
 	if format == "" {
 		return nil, &ParsePrinterError{
 			Explanation: "required to be non-empty",
@@ -165,6 +339,21 @@ func ParsePrinter(format string) (*Printer, error) {
 		},
 	}
 
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.escapes {
+		var err error
+		if format, err = unescape(format); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.crlf {
+		format = toCRLF(format)
+	}
+
 	for {
 		i := strings.IndexByte(format, '%')
 		if i == -1 {
@@ -180,7 +369,7 @@ func ParsePrinter(format string) (*Printer, error) {
 			break
 		}
 
-		op, advance, err := parseArg(format[i+1:], &p.amp)
+		op, advance, err := parseArg(format[i+1:], p)
 		if err != nil {
 			return nil, err
 		}
@@ -195,8 +384,8 @@ func ParsePrinter(format string) (*Printer, error) {
 // MustParsePrinter is the same as ParsePrinter, but panics on non-nil error.
 // Use this if you are sure the format string is valid. See the documentation for
 // ParsePrinter to see how a format string is built.
-func MustParsePrinter(format string) *Printer {
-	p, err := ParsePrinter(format)
+func MustParsePrinter(format string, opts ...PrinterOption) *Printer {
+	p, err := ParsePrinter(format, opts...)
 	if err != nil {
 		panic(err)
 	}
@@ -204,6 +393,64 @@ func MustParsePrinter(format string) *Printer {
 	return p
 }
 
+// toCRLF replaces the line feeds which aren't preceded by a carriage return
+// with a carriage return and a line feed.
+func toCRLF(format string) string {
+	var sb strings.Builder
+	sb.Grow(len(format))
+
+	for i := 0; i < len(format); i++ {
+		if format[i] == '\n' && (i == 0 || format[i-1] != '\r') {
+			sb.WriteByte('\r')
+		}
+		sb.WriteByte(format[i])
+	}
+
+	return sb.String()
+}
+
+func unescape(format string) (string, error) {
+	i := strings.IndexByte(format, '\\')
+	if i == -1 {
+		return format, nil
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(format))
+
+	for ; i != -1; i = strings.IndexByte(format, '\\') {
+		sb.WriteString(format[:i])
+		if i+1 == len(format) {
+			return "", &ParsePrinterError{
+				Format:      format[i:],
+				Explanation: "unterminated escape sequence",
+			}
+		}
+
+		switch c := format[i+1]; c {
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case '\\':
+			sb.WriteByte('\\')
+		default:
+			return "", &ParsePrinterError{
+				Format:      format[i:],
+				Explanation: "invalid escape sequence \"\\" + string(c) + "\"",
+			}
+		}
+
+		format = format[i+2:]
+	}
+
+	sb.WriteString(format)
+
+	return sb.String(), nil
+}
+
 const (
 	verbLiteralPercent  = '%'
 	verbVerticesCount   = 'n'
@@ -212,9 +459,171 @@ const (
 	verbCosts           = 'w'
 	verbVertices        = 'N'
 	verbEdges           = 'M'
+	verbAdjacencyList   = 'L'
+	verbCostMatrix      = 'W'
+	verbIncidenceMatrix = 'I'
+	verbDegrees         = 'd'
+	verbInDegrees       = 'i'
+	verbOutDegrees      = 'o'
+	verbDOT             = 'G'
+
+	flagLabels = '#'
+	flagDegree = '+'
+
+	argNone     = "none"
+	argDiagonal = "diag"
+	argOut      = "out"
+	argIn       = "in"
+	argSort     = "sort"
+
+	argSeparator    = "sep"
+	argRowSeparator = "row"
+
+	sortAscending  = "asc"
+	sortDescending = "desc"
 )
 
-func parseArg(text string, amp *sync.Pool) (operation, int, error) {
+type verbFlags struct {
+	labels bool
+	degree bool
+	// raw holds the flags as they were written.
+	raw string
+	// args holds the named arguments of the verb.
+	args map[string]string
+}
+
+// check returns an error if any of the flags or arguments is not allowed for the verb.
+func (f verbFlags) check(text string, verb byte, allowedFlags string, allowedArgs ...string) error {
+	for i := 0; i < len(f.raw); i++ {
+		if strings.IndexByte(allowedFlags, f.raw[i]) == -1 {
+			return invalidFlagError(text, f.raw[i], verb)
+		}
+	}
+
+outer:
+	for name := range f.args {
+		for _, allowed := range allowedArgs {
+			if name == allowed {
+				continue outer
+			}
+		}
+
+		return &ParsePrinterError{
+			Format:      text,
+			Explanation: "argument \"" + name + "\" is not allowed for verb \"" + string(verb) + "\"",
+		}
+	}
+
+	return nil
+}
+
+// parseFlags parses the modifiers written between the "%" sign and the cost function:
+// the optional arguments, in square brackets, followed by the flags.
+func parseFlags(text string) (verbFlags, int, error) {
+	var f verbFlags
+
+	args, i, err := parseArgs(text)
+	if err != nil {
+		return f, 0, err
+	}
+	f.args = args
+
+	start := i
+
+loop:
+	for ; i < len(text); i++ {
+		switch text[i] {
+		case flagLabels:
+			f.labels = true
+		case flagDegree:
+			f.degree = true
+		default:
+			break loop
+		}
+	}
+
+	f.raw = text[start:i]
+	return f, i, nil
+}
+
+// parseArgs parses the arguments of a verb, if text starts with them:
+//
+//	[name1=value1,name2="value, 2"]
+//
+// Values which contain commas or closing brackets must be quoted.
+// Quoted values can't contain double quotes.
+func parseArgs(text string) (map[string]string, int, error) {
+	if text == "" || text[0] != '[' {
+		return nil, 0, nil
+	}
+
+	unterminated := &ParsePrinterError{
+		Format:      text,
+		Explanation: "unterminated arguments",
+	}
+
+	args := map[string]string{}
+	i := 1
+
+	for {
+		eq := strings.IndexAny(text[i:], "=,]")
+		if eq == -1 {
+			return nil, 0, unterminated
+		}
+		if text[i+eq] != '=' || eq == 0 {
+			return nil, 0, &ParsePrinterError{
+				Format:      text,
+				Explanation: "arguments must be written as name=value",
+			}
+		}
+
+		name := text[i : i+eq]
+		i += eq + 1
+
+		var value string
+		if i < len(text) && text[i] == '"' {
+			end := strings.IndexByte(text[i+1:], '"')
+			if end == -1 {
+				return nil, 0, unterminated
+			}
+			value = text[i+1 : i+1+end]
+			i += end + 2
+		} else {
+			end := strings.IndexAny(text[i:], ",]")
+			if end == -1 {
+				return nil, 0, unterminated
+			}
+			value = text[i : i+end]
+			i += end
+		}
+
+		if _, ok := args[name]; ok {
+			return nil, 0, &ParsePrinterError{
+				Format:      text,
+				Explanation: "argument \"" + name + "\" is given more than once",
+			}
+		}
+		args[name] = value
+
+		if i == len(text) {
+			return nil, 0, unterminated
+		}
+
+		switch text[i] {
+		case ']':
+			return args, i + 1, nil
+		case ',':
+			i++
+		default:
+			return nil, 0, &ParsePrinterError{
+				Format:      text,
+				Explanation: "expected \",\" or \"]\" after argument \"" + name + "\"",
+			}
+		}
+	}
+}
+
+func parseArg(text string, p *Printer) (operation, int, error) {
 	if text == "" {
 		return nil, 0, &ParsePrinterError{
 			Explanation: "unexpected end",
@@ -225,75 +634,217 @@ func parseArg(text string, amp *sync.Pool) (operation, int, error) {
 	case verbLiteralPercent:
 		return textOperation("%"), 1, nil
 	case verbVerticesCount:
-		return verticesCountOperation, 1, nil
+		return countOperation(verbVerticesCount), 1, nil
 	case verbEdgesCount:
-		return edgesCountOperation, 1, nil
-	case verbAdjacencyMatrix:
-		return newAdjacencyMatrixOperation(amp), 1, nil
-	default:
-		costFn, advance, err := parseCostFunction(text)
-		if err != nil {
-			return nil, 0, err
+		return countOperation(verbEdgesCount), 1, nil
+	}
+
+	flags, flagsAdvance, err := parseFlags(text)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	op, advance, err := parseFlaggedArg(text[flagsAdvance:], flags, p)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return op, flagsAdvance + advance, nil
+}
+
+func parseFlaggedArg(text string, flags verbFlags, p *Printer) (operation, int, error) {
+	costFn, advance, err := parseCostFunction(text)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if advance == len(text) {
+		return nil, 0, &ParsePrinterError{
+			Format:      text,
+			Explanation: "missing verb",
 		}
+	}
 
-		if advance == len(text) {
-			return nil, 0, &ParsePrinterError{
+	c := text[advance]
+	switch c {
+	case verbCosts:
+		err = flags.check(text, c, "", argSeparator)
+	case verbDOT:
+		err = flags.check(text, c, "")
+	case verbVertices, verbEdges:
+		err = flags.check(text, c, string(flagLabels), argSort, argSeparator, argRowSeparator)
+		if order := flags.args[argSort]; err == nil && order != "" && !validOrder(order) {
+			err = &ParsePrinterError{
 				Format:      text,
-				Explanation: "missing verb",
+				Explanation: "invalid order \"" + order + "\", expected one of \"" + strings.Join([]string{orderID, orderLabel, orderCost, orderCostDesc}, "\", \"") + "\"",
 			}
 		}
+	case verbAdjacencyList:
+		err = flags.check(text, c, string([]byte{flagLabels, flagDegree}), argSeparator, argRowSeparator)
+	case verbCostMatrix:
+		err = flags.check(text, c, "", argNone, argDiagonal, argSeparator, argRowSeparator)
+	case verbAdjacencyMatrix:
+		if err = noCostFunction(text, c, advance); err == nil {
+			err = flags.check(text, c, "", argSeparator, argRowSeparator)
+		}
+	case verbIncidenceMatrix:
+		if err = noCostFunction(text, c, advance); err == nil {
+			err = flags.check(text, c, "", argOut, argIn, argSeparator, argRowSeparator)
+		}
+	case verbDegrees, verbInDegrees, verbOutDegrees:
+		if err = noCostFunction(text, c, advance); err == nil {
+			err = flags.check(text, c, "", argSort, argSeparator)
+		}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
 
-		switch c := text[advance]; c {
-		case verbCosts:
-			if costFn == nil {
-				return costsOperation(costFunction{ratio: 1, round: noopRound}), advance + 1, nil
-			}
-			return costsOperation(*costFn), advance + 1, nil
-		case verbVertices:
-			return &verticesOperation{cost: costFn}, advance + 1, nil
-		case verbEdges:
-			return &edgesOperation{cost: costFn}, advance + 1, nil
-		default:
+	sep := separators{field: " ", row: p.newline()}
+	if v, ok := flags.args[argSeparator]; ok {
+		sep.field = v
+	}
+	if v, ok := flags.args[argRowSeparator]; ok {
+		sep.row = v
+	}
+
+	switch c {
+	case verbCosts:
+		op := &costsOperation{cost: identityCostFunction(), sep: sep}
+		if costFn != nil {
+			op.cost = *costFn
+		}
+		return op, advance + 1, nil
+	case verbDOT:
+		op := &dotOperation{cost: identityCostFunction()}
+		if costFn != nil {
+			op.cost = *costFn
+		}
+		return op, advance + 1, nil
+	case verbVertices:
+		return &verticesOperation{cost: costFn, labels: flags.labels, order: flags.args[argSort], sep: sep}, advance + 1, nil
+	case verbEdges:
+		return &edgesOperation{cost: costFn, labels: flags.labels, order: flags.args[argSort], sep: sep}, advance + 1, nil
+	case verbAdjacencyList:
+		return &adjacencyListOperation{cost: costFn, labels: flags.labels, degree: flags.degree, sep: sep}, advance + 1, nil
+	case verbAdjacencyMatrix:
+		return &adjacencyMatrixOperation{pool: &p.amp, sep: sep}, advance + 1, nil
+	case verbCostMatrix:
+		op := &costMatrixOperation{cost: identityCostFunction(), none: "0", sep: sep}
+		if costFn != nil {
+			op.cost = *costFn
+		}
+		if none, ok := flags.args[argNone]; ok {
+			op.none = none
+		}
+		op.diagonal, op.hasDiagonal = flags.args[argDiagonal]
+		return op, advance + 1, nil
+	case verbIncidenceMatrix:
+		out, in := "1", "-1"
+		if v, ok := flags.args[argOut]; ok {
+			out = v
+		}
+		if v, ok := flags.args[argIn]; ok {
+			in = v
+		}
+		return &incidenceMatrixOperation{out: out, in: in, sep: sep}, advance + 1, nil
+	case verbDegrees, verbInDegrees, verbOutDegrees:
+		op := &degreesOperation{kind: c, sort: flags.args[argSort], sep: sep}
+		if op.sort != "" && op.sort != sortAscending && op.sort != sortDescending {
 			return nil, 0, &ParsePrinterError{
 				Format:      text,
-				Explanation: "invalid verb \"" + string(c) + "\"",
+				Explanation: "invalid sort order \"" + op.sort + "\", expected \"" + sortAscending + "\" or \"" + sortDescending + "\"",
 			}
 		}
+		return op, advance + 1, nil
+	case verbLiteralPercent, verbVerticesCount, verbEdgesCount:
+		if advance == 0 {
+			if err := flags.check(text, c, ""); err != nil {
+				return nil, 0, err
+			}
+		}
+		fallthrough
+	default:
+		return nil, 0, &ParsePrinterError{
+			Format:      text,
+			Explanation: "invalid verb \"" + string(c) + "\"",
+		}
+	}
+}
+
+func noCostFunction(text string, verb byte, advance int) error {
+	if advance == 0 {
+		return nil
+	}
+
+	return &ParsePrinterError{
+		Format:      text,
+		Explanation: "verb \"" + string(verb) + "\" does not accept a cost function",
+	}
+}
+
+func invalidFlagError(text string, flag, verb byte) error {
+	return &ParsePrinterError{
+		Format:      text,
+		Explanation: "flag \"" + string(flag) + "\" is not allowed for verb \"" + string(verb) + "\"",
 	}
 }
 
 type costFunction struct {
 	ratio float64
 	round func(float64) float64
+	// precision is the number of decimals printed, or -1 for
+	// the smallest number of decimals which represent the cost exactly.
+	precision int
+}
+
+func identityCostFunction() costFunction {
+	return costFunction{ratio: 1, round: noopRound, precision: -1}
 }
 
 func (c costFunction) Cost(v float64) float64 {
 	return c.round(v * c.ratio)
 }
 
+// append appends the formatted cost to b.
+func (c costFunction) append(b []byte, v float64) []byte {
+	v = c.Cost(v)
+	if v == 0 {
+		// Avoid printing negative zero, for example after rounding -0.2.
+		v = 0
+	}
+
+	return strconv.AppendFloat(b, v, 'f', c.precision, 64)
+}
+
 const (
 	roundingModeNone  = 'X'
 	roundingModeFloor = 'F'
 	roundingModeRound = 'R'
 	roundingModeCeil  = 'C'
+
+	formatPrecision = 'P'
+	formatInteger   = 'D'
+
+	// maxPrecision is the largest number of decimals a cost can be printed with.
+	maxPrecision = 20
 )
 
 func noopRound(v float64) float64 { return v }
 
 func parseCostFunction(s string) (*costFunction, int, error) {
 	i := strings.IndexFunc(s, func(r rune) bool {
-		return (r <= '0' || r >= '9') && r != '.'
+		return (r < '0' || r > '9') && r != '.'
 	})
-	advance := i + 1
 	if i == -1 {
 		i = len(s)
-		advance = i
 	}
 
-	fn := costFunction{ratio: 1, round: noopRound}
-	if ratioStr := s[:i]; ratioStr != "" {
+	fn := identityCostFunction()
+	parsed := i > 0
+	if parsed {
 		var err error
-		fn.ratio, err = strconv.ParseFloat(ratioStr, 64)
+		fn.ratio, err = strconv.ParseFloat(s[:i], 64)
 		if err != nil {
 			return nil, 0, &ParsePrinterError{
 				Format:      s,
@@ -303,7 +854,8 @@ func parseCostFunction(s string) (*costFunction, int, error) {
 		}
 	}
 
-	if i != len(s) {
+	rounding := i < len(s)
+	if rounding {
 		switch s[i] {
 		case roundingModeNone:
 		case roundingModeFloor:
@@ -313,89 +865,419 @@ func parseCostFunction(s string) (*costFunction, int, error) {
 		case roundingModeCeil:
 			fn.round = math.Ceil
 		default:
-			advance--
-			if advance == 0 {
-				return nil, 0, nil
+			rounding = false
+		}
+	}
+	if rounding {
+		parsed = true
+		i++
+	}
+
+	if i < len(s) {
+		switch s[i] {
+		case formatInteger:
+			parsed = true
+			fn.precision = 0
+			if !rounding {
+				fn.round = math.Round
+			}
+			i++
+		case formatPrecision:
+			parsed = true
+			j := i + 1
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			if j == i+1 {
+				return nil, 0, &ParsePrinterError{
+					Format:      s,
+					Explanation: "missing number of decimals after \"" + string(formatPrecision) + "\"",
+				}
 			}
+			precision, err := strconv.Atoi(s[i+1 : j])
+			if err != nil || precision > maxPrecision {
+				return nil, 0, &ParsePrinterError{
+					Format:      s,
+					Explanation: "invalid number of decimals",
+					Reason:      err,
+				}
+			}
+			fn.precision = precision
+			i = j
 		}
 	}
 
-	return &fn, advance, nil
+	if !parsed {
+		return nil, 0, nil
+	}
+
+	return &fn, i, nil
+}
+
+// separators are written between the values printed by a verb.
+type separators struct {
+	// field separates the values on the same row.
+	field string
+	// row separates the rows.
+	row string
 }
 
 type textOperation string
 
-func (t textOperation) apply(w writer, _ *Graph) (int, error) {
+func (t textOperation) apply(w writer, _ *state) (int, error) {
 	return w.WriteString(string(t))
 }
 
-var (
-	verticesCountOperation operationFunc = func(w writer, g *Graph) (int, error) {
-		return w.Write(strconv.AppendInt(nil, int64(len(g.Nodes)), 10))
-	}
-	edgesCountOperation operationFunc = func(w writer, g *Graph) (int, error) {
-		return w.Write(strconv.AppendInt(nil, int64(len(g.Edges)), 10))
+// countOperation prints the number of vertices or edges, depending on its verb.
+type countOperation byte
+
+func (c countOperation) apply(w writer, g *state) (int, error) {
+	count := len(g.Nodes)
+	if c == verbEdgesCount {
+		count = len(g.Edges)
 	}
-)
 
-func newAdjacencyMatrixOperation(p *sync.Pool) operation {
-	return operationFunc(func(w writer, g *Graph) (int, error) {
-		nodes := len(g.Nodes)
-		grow(w, 2*nodes*(nodes-1))
+	return w.Write(strconv.AppendInt(nil, int64(count), 10))
+}
 
-		var n int
-		var err error
+type adjacencyMatrixOperation struct {
+	pool *sync.Pool
+	sep  separators
+}
 
-		m := p.Get().(*big.Int)
-		defer p.Put(m)
-		m.SetUint64(0)
+func (a *adjacencyMatrixOperation) apply(w writer, g *state) (int, error) {
+	ids := g.ids()
+	nodes := ids.n
+	grow(w, 2*nodes*nodes)
 
-		for _, e := range g.Edges {
-			m.SetBit(m, e.Src*nodes+e.Dst, 1)
-			if !e.Directed {
-				m.SetBit(m, e.Dst*nodes+e.Src, 1)
+	var n int
+	var err error
+
+	m := a.pool.Get().(*big.Int)
+	defer a.pool.Put(m)
+	m.SetUint64(0)
+
+	for _, e := range g.Edges {
+		src, okSrc := ids.position(e.Src)
+		dst, okDst := ids.position(e.Dst)
+		if !okSrc || !okDst {
+			continue
+		}
+
+		m.SetBit(m, src*nodes+dst, 1)
+		if !e.Directed {
+			m.SetBit(m, dst*nodes+src, 1)
+		}
+	}
+
+	var k int
+
+	for i := 0; i < nodes; i++ {
+		if i > 0 {
+			k, err = w.WriteString(a.sep.row)
+			if n += k; err != nil {
+				return n, err
 			}
 		}
 
-		for i, a := range g.Nodes {
-			if i > 0 {
-				if err = w.WriteByte('\n'); err != nil {
+		for j := 0; j < nodes; j++ {
+			if j > 0 {
+				k, err = w.WriteString(a.sep.field)
+				if n += k; err != nil {
 					return n, err
 				}
-				n++
 			}
 
-			for i, b := range g.Nodes {
-				if i > 0 {
-					if err = w.WriteByte(' '); err != nil {
-						return n, err
-					}
-					n++
-				}
+			if m.Bit(i*nodes+j) == 1 {
+				err = w.WriteByte('1')
+			} else {
+				err = w.WriteByte('0')
+			}
 
-				if m.Bit(a.ID*nodes+b.ID) == 1 {
-					err = w.WriteByte('1')
-				} else {
-					err = w.WriteByte('0')
-				}
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
 
-				if err != nil {
-					return n, err
-				}
-				n++
+	return n, nil
+}
+
+const (
+	incidenceNone byte = iota
+	incidenceBoth
+	incidenceOut
+	incidenceIn
+)
+
+type incidenceMatrixOperation struct {
+	out string
+	in  string
+	sep separators
+}
+
+func (o *incidenceMatrixOperation) apply(w writer, g *state) (int, error) {
+	ids := g.ids()
+	nodes, edges := ids.n, len(g.Edges)
+	m := make([]byte, nodes*edges)
+
+	for j, e := range g.Edges {
+		src, okSrc := ids.position(e.Src)
+		dst, okDst := ids.position(e.Dst)
+
+		if !e.Directed {
+			if okSrc {
+				m[src*edges+j] = incidenceBoth
+			}
+			if okDst {
+				m[dst*edges+j] = incidenceBoth
 			}
+			continue
 		}
 
-		return n, nil
-	})
+		if okDst {
+			m[dst*edges+j] = incidenceIn
+		}
+		if okSrc {
+			m[src*edges+j] = incidenceOut
+		}
+	}
+
+	b := []byte{}
+	var n, k int
+	var err error
+
+	for i := 0; i < nodes; i++ {
+		b = b[:0]
+		if i > 0 {
+			b = append(b, o.sep.row...)
+		}
+
+		for j := 0; j < edges; j++ {
+			if j > 0 {
+				b = append(b, o.sep.field...)
+			}
+
+			switch m[i*edges+j] {
+			case incidenceNone:
+				b = append(b, '0')
+			case incidenceBoth:
+				b = append(b, '1')
+			case incidenceOut:
+				b = append(b, o.out...)
+			case incidenceIn:
+				b = append(b, o.in...)
+			}
+		}
+
+		k, err = w.Write(b)
+		n += k
+		if err != nil {
+			break
+		}
+	}
+
+	return n, err
+}
+
+type degreesOperation struct {
+	// kind is the verb of the operation, which tells what degree is printed.
+	kind byte
+	sort string
+	sep  separators
+}
+
+func (d *degreesOperation) apply(w writer, g *state) (int, error) {
+	ids := g.ids()
+	degrees := make([]int, ids.n)
+
+	add := func(id int) {
+		if p, ok := ids.position(id); ok {
+			degrees[p]++
+		}
+	}
+
+	for _, e := range g.Edges {
+		switch {
+		case d.kind == verbDegrees:
+			add(e.Src)
+			add(e.Dst)
+		case !bool(e.Directed):
+			// An undirected edge is both an incoming and an outgoing
+			// edge for each of its vertices.
+			add(e.Src)
+			if e.Src != e.Dst {
+				add(e.Dst)
+			}
+		case d.kind == verbInDegrees:
+			add(e.Dst)
+		default:
+			add(e.Src)
+		}
+	}
+
+	switch d.sort {
+	case sortAscending:
+		sort.Ints(degrees)
+	case sortDescending:
+		sort.Sort(sort.Reverse(sort.IntSlice(degrees)))
+	}
+
+	b := []byte{}
+	for i, deg := range degrees {
+		if i > 0 {
+			b = append(b, d.sep.field...)
+		}
+		b = strconv.AppendInt(b, int64(deg), 10)
+	}
+
+	return w.Write(b)
+}
+
+type adjacencyListOperation struct {
+	degree bool
+	labels bool
+	cost   *costFunction
+	sep    separators
+}
+
+type neighbour struct {
+	id   int
+	cost float64
+}
+
+func (a *adjacencyListOperation) apply(w writer, g *state) (int, error) {
+	ids := g.ids()
+	adj := make([][]neighbour, ids.n)
+
+	for _, e := range g.Edges {
+		src, okSrc := ids.position(e.Src)
+		dst, okDst := ids.position(e.Dst)
+		if !okSrc || !okDst {
+			continue
+		}
+
+		adj[src] = append(adj[src], neighbour{id: e.Dst, cost: e.Cost})
+		if !e.Directed && src != dst {
+			adj[dst] = append(adj[dst], neighbour{id: e.Src, cost: e.Cost})
+		}
+	}
+
+	b := []byte{}
+	var n, m int
+	var err error
+
+	for i, neighbours := range adj {
+		b = b[:0]
+		if i > 0 {
+			b = append(b, a.sep.row...)
+		}
+
+		if a.degree {
+			b = strconv.AppendInt(b, int64(len(neighbours)), 10)
+		} else {
+			b = g.appendName(b, ids.id(i), a.labels)
+			b = append(b, ':')
+		}
+
+		for _, nb := range neighbours {
+			b = append(b, a.sep.field...)
+			b = g.appendName(b, nb.id, a.labels)
+			if a.cost != nil {
+				b = append(b, a.sep.field...)
+				b = a.cost.append(b, nb.cost)
+			}
+		}
+
+		m, err = w.Write(b)
+		n += m
+		if err != nil {
+			break
+		}
+	}
+
+	return n, err
+}
+
+type costMatrixOperation struct {
+	sep         separators
+	cost        costFunction
+	none        string
+	diagonal    string
+	hasDiagonal bool
+}
+
+func (c *costMatrixOperation) apply(w writer, g *state) (int, error) {
+	ids := g.ids()
+	nodes := ids.n
+	costs := make([]float64, nodes*nodes)
+	present := make([]bool, nodes*nodes)
+
+	set := func(i int, cost float64) {
+		if !present[i] || cost < costs[i] {
+			costs[i] = cost
+			present[i] = true
+		}
+	}
+
+	for _, e := range g.Edges {
+		src, okSrc := ids.position(e.Src)
+		dst, okDst := ids.position(e.Dst)
+		if !okSrc || !okDst {
+			continue
+		}
+
+		set(src*nodes+dst, e.Cost)
+		if !e.Directed {
+			set(dst*nodes+src, e.Cost)
+		}
+	}
+
+	b := []byte{}
+	var n, m int
+	var err error
+
+	for i := 0; i < nodes; i++ {
+		b = b[:0]
+		if i > 0 {
+			b = append(b, c.sep.row...)
+		}
+
+		for j := 0; j < nodes; j++ {
+			if j > 0 {
+				b = append(b, c.sep.field...)
+			}
+
+			k := i*nodes + j
+			if present[k] {
+				b = c.cost.append(b, costs[k])
+			} else if i == j && c.hasDiagonal {
+				b = append(b, c.diagonal...)
+			} else {
+				b = append(b, c.none...)
+			}
+		}
+
+		m, err = w.Write(b)
+		n += m
+		if err != nil {
+			break
+		}
+	}
+
+	return n, err
 }
 
 type verticesOperation struct {
 	prefixCost bool
+	labels     bool
 	cost       *costFunction
+	order      string
+	sep        separators
 }
 
-func (v *verticesOperation) apply(w writer, g *Graph) (int, error) {
+func (v *verticesOperation) apply(w writer, g *state) (int, error) {
 	b := []byte{}
 	var n, m int
 	var err error
@@ -405,33 +1287,38 @@ func (v *verticesOperation) apply(w writer, g *Graph) (int, error) {
 			return nil
 		}
 		if !v.prefixCost {
-			if err = w.WriteByte(' '); err != nil {
+			m, err = w.WriteString(v.sep.field)
+			if n += m; err != nil {
 				return err
 			}
-			n++
 		}
-		m, err = w.Write(strconv.AppendFloat(b[:0], v.cost.Cost(nd.Cost), 'f', -1, 64))
+		m, err = w.Write(v.cost.append(b[:0], nd.Cost))
 		n += m
 		return err
 	}
 	writeVertex := func(nd *Node) error {
 		if v.prefixCost && v.cost != nil {
-			if err = w.WriteByte(' '); err != nil {
+			m, err = w.WriteString(v.sep.field)
+			if n += m; err != nil {
 				return err
 			}
-			n++
 		}
-		m, err = w.Write(strconv.AppendInt(b[:0], int64(nd.ID), 10))
+		if v.labels {
+			b = g.appendLabel(b[:0], nd.ID, nd.Label)
+		} else {
+			b = strconv.AppendInt(b[:0], int64(g.id(nd.ID)), 10)
+		}
+		m, err = w.Write(b)
 		n += m
 		return err
 	}
 
-	for i, node := range g.Nodes {
+	for i, node := range g.orderedNodes(v.order) {
 		if i > 0 {
-			if err = w.WriteByte('\n'); err != nil {
+			m, err = w.WriteString(v.sep.row)
+			if n += m; err != nil {
 				return n, err
 			}
-			n++
 		}
 
 		if v.prefixCost {
@@ -456,10 +1343,13 @@ func (v *verticesOperation) apply(w writer, g *Graph) (int, error) {
 
 type edgesOperation struct {
 	prefixCost bool
+	labels     bool
 	cost       *costFunction
+	order      string
+	sep        separators
 }
 
-func (v *edgesOperation) apply(w writer, g *Graph) (int, error) {
+func (v *edgesOperation) apply(w writer, g *state) (int, error) {
 	b := []byte{}
 	var n, m int
 	var err error
@@ -469,35 +1359,35 @@ func (v *edgesOperation) apply(w writer, g *Graph) (int, error) {
 			return nil
 		}
 		if !v.prefixCost {
-			if err = w.WriteByte(' '); err != nil {
+			m, err = w.WriteString(v.sep.field)
+			if n += m; err != nil {
 				return err
 			}
-			n++
 		}
-		m, err = w.Write(strconv.AppendFloat(b[:0], v.cost.Cost(e.Cost), 'f', -1, 64))
+		m, err = w.Write(v.cost.append(b[:0], e.Cost))
 		n += m
 		return err
 	}
 	writeEdge := func(e *Edge) error {
 		if v.prefixCost && v.cost != nil {
-			if err = w.WriteByte(' '); err != nil {
+			m, err = w.WriteString(v.sep.field)
+			if n += m; err != nil {
 				return err
 			}
-			n++
 		}
-		b = strconv.AppendInt(b[:0], int64(e.Src), 10)
-		b = append(b, ' ')
-		m, err = w.Write(strconv.AppendInt(b, int64(e.Dst), 10))
+		b = g.appendName(b[:0], e.Src, v.labels)
+		b = append(b, v.sep.field...)
+		m, err = w.Write(g.appendName(b, e.Dst, v.labels))
 		n += m
 		return err
 	}
 
-	for i, e := range g.Edges {
+	for i, e := range g.orderedEdges(v.order) {
 		if i > 0 {
-			if err = w.WriteByte('\n'); err != nil {
+			m, err = w.WriteString(v.sep.row)
+			if n += m; err != nil {
 				return n, err
 			}
-			n++
 		}
 
 		if v.prefixCost {
@@ -520,23 +1410,25 @@ func (v *edgesOperation) apply(w writer, g *Graph) (int, error) {
 	return n, err
 }
 
-type costsOperation costFunction
+type costsOperation struct {
+	cost costFunction
+	sep  separators
+}
 
-func (c costsOperation) apply(w writer, g *Graph) (int, error) {
+func (c *costsOperation) apply(w writer, g *state) (int, error) {
 	b := []byte{}
-	fn := costFunction(c)
 	var n, m int
 	var err error
 
 	for i, node := range g.Nodes {
 		if i > 0 {
-			if err = w.WriteByte(' '); err != nil {
+			m, err = w.WriteString(c.sep.field)
+			if n += m; err != nil {
 				return n, err
 			}
-			n++
 		}
 
-		m, err = w.Write(strconv.AppendFloat(b[:0], fn.Cost(node.Cost), 'f', -1, 64))
+		m, err = w.Write(c.cost.append(b[:0], node.Cost))
 		n += m
 		if err != nil {
 			break
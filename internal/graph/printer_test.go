@@ -9,9 +9,36 @@ import (
 	"github.com/tmaxmax/xml-to-graph/internal/graph"
 )
 
+var labeledGraph = graph.Graph{
+	Nodes: []graph.Node{
+		{ID: 1, Label: "Arad"},
+		{ID: 2},
+		{ID: 3, Label: "Cluj"},
+	},
+	Edges: []graph.Edge{
+		{Src: 1, Dst: 2, Cost: 1},
+		{Src: 2, Dst: 3, Cost: 0.5},
+	},
+}
+
+var gappedGraph = graph.Graph{
+	Nodes: []graph.Node{
+		{ID: 1},
+		{ID: 7},
+		{ID: 2},
+		{ID: 4},
+	},
+	Edges: []graph.Edge{
+		{Src: 1, Dst: 2},
+		{Src: 7, Dst: 4},
+		{Src: 2, Dst: 9},
+	},
+}
+
 func TestParsePrinter(t *testing.T) {
 	type testCase struct {
 		format string
+		opts   []graph.PrinterOption
 		hasErr bool
 		output string
 		graph  graph.Graph
@@ -24,6 +51,24 @@ func TestParsePrinter(t *testing.T) {
 		{format: "%5z", hasErr: true},
 		{format: "%.4AN", hasErr: true},
 		{format: "%x", hasErr: true},
+		{format: "%#w", hasErr: true},
+		{format: "%#a", hasErr: true},
+		{format: "%#", hasErr: true},
+		{format: "%+M", hasErr: true},
+		{format: "%+n", hasErr: true},
+		{format: "%[none=1]n", hasErr: true},
+		{format: "%[none=1]M", hasErr: true},
+		{format: "%[none=1W", hasErr: true},
+		{format: "%[none]W", hasErr: true},
+		{format: "%[none=1,none=2]W", hasErr: true},
+		{format: `%[none="1]W`, hasErr: true},
+		{format: "%2I", hasErr: true},
+		{format: "%[none=1]I", hasErr: true},
+		{format: "%[sort=up]d", hasErr: true},
+		{format: "%#d", hasErr: true},
+		{format: "%2o", hasErr: true},
+		{format: `%n\q`, opts: []graph.PrinterOption{graph.Escapes()}, hasErr: true},
+		{format: `%n\`, opts: []graph.PrinterOption{graph.Escapes()}, hasErr: true},
 		{
 			format: "Nodes: %n\n%N\n\nEdges: %m\n%2RM\n\nCosts: %w\n\nAdjacency matrix:\n%a\n",
 			graph: graph.Graph{
@@ -56,11 +101,167 @@ Adjacency matrix:
 1 1 0
 `,
 		},
+		{
+			format: "%#N\n%#2M\n",
+			graph:  labeledGraph,
+			output: "Arad\n2\nCluj\nArad 2 2\n2 Cluj 1\n",
+		},
+		{
+			format: "%#M",
+			opts:   []graph.PrinterOption{graph.LabelFallback("?")},
+			graph:  labeledGraph,
+			output: "Arad ?\n? Cluj",
+		},
+		{
+			format: "%N\n%M\n%a\n",
+			graph:  gappedGraph,
+			output: "1\n7\n2\n4\n1 2\n7 4\n2 9\n0 1 0 0\n1 0 0 0\n0 0 0 1\n0 0 1 0\n",
+		},
+		{
+			format: "%N\n%M\n%a\n",
+			opts:   []graph.PrinterOption{graph.IDBase(1)},
+			graph:  gappedGraph,
+			output: "1\n4\n2\n3\n1 2\n4 3\n2 5\n0 1 0 0\n1 0 0 0\n0 0 0 1\n0 0 1 0\n",
+		},
+		{
+			format: "%N\n%M\n",
+			opts:   []graph.PrinterOption{graph.IDBase(0)},
+			graph:  gappedGraph,
+			output: "0\n3\n1\n2\n0 1\n3 2\n1 4\n",
+		},
+		{
+			format: "%N\n%M\n",
+			opts:   []graph.PrinterOption{graph.IDBase(0), graph.PreserveIDs()},
+			graph:  gappedGraph,
+			output: "1\n7\n2\n4\n1 2\n7 4\n2 9\n",
+		},
+		{
+			format: "%N\n%M\n",
+			opts:   []graph.PrinterOption{graph.PreserveIDs(), graph.IDBase(0)},
+			graph:  gappedGraph,
+			output: "1\n7\n2\n4\n1 2\n7 4\n2 9\n",
+		},
+		{
+			format: `%n\t%m\r\n\\%M\n`,
+			opts:   []graph.PrinterOption{graph.Escapes()},
+			graph:  labeledGraph,
+			output: "3\t2\r\n\\1 2\n2 3\n",
+		},
+		{
+			format: "%L\n%+1L\n",
+			graph:  labeledGraph,
+			output: "1: 2\n2: 1 3\n3: 2\n1 2 1\n2 1 1 3 0.5\n1 2 0.5\n",
+		},
+		{
+			format: "%#L",
+			graph:  expected,
+			output: "Arad: 2\n2: Arad\nCluj: 2",
+		},
+		{
+			format: "%W\n%[none=INF,diag=0]2W\n%[none=\"[,]\"]W",
+			graph: graph.Graph{
+				Nodes: []graph.Node{{ID: 1}, {ID: 2}, {ID: 3}},
+				Edges: []graph.Edge{
+					{Src: 1, Dst: 2, Cost: 4},
+					{Src: 2, Dst: 1, Cost: 3, Directed: true},
+					{Src: 2, Dst: 3, Cost: 1.5, Directed: true},
+				},
+			},
+			output: "0 4 0\n3 0 1.5\n0 0 0\n0 8 INF\n6 0 3\nINF INF 0\n[,] 4 [,]\n3 [,] 1.5\n[,] [,] [,]",
+		},
+		{
+			format: "%I\n%[out=-1,in=1]I",
+			graph:  expected,
+			output: "1 0\n1 -1\n0 1\n1 0\n1 1\n0 -1",
+		},
+		{
+			format: "%d\n%i\n%o\n%[sort=asc]o\n%[sort=desc]d",
+			graph: graph.Graph{
+				Nodes: []graph.Node{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}},
+				Edges: []graph.Edge{
+					{Src: 1, Dst: 2},
+					{Src: 3, Dst: 2, Directed: true},
+					{Src: 3, Dst: 4, Directed: true},
+					{Src: 4, Dst: 4},
+				},
+			},
+			output: "1 2 2 3\n1 2 0 2\n1 1 2 1\n1 1 1 2\n3 2 2 1",
+		},
+		{
+			format: `%[sep=",",row=" "]1M|%[sep="\t"]a|%[sep=;]d|%[sep=", ",row=";"]L`,
+			opts:   []graph.PrinterOption{graph.Escapes()},
+			graph: graph.Graph{
+				Nodes: []graph.Node{{ID: 1}, {ID: 2}, {ID: 3}},
+				Edges: []graph.Edge{
+					{Src: 1, Dst: 2, Cost: 2},
+					{Src: 2, Dst: 3, Cost: 1.5, Directed: true},
+				},
+			},
+			output: "1,2,2 2,3,1.5|0\t1\t0\n1\t0\t1\n0\t0\t0|1;2;1|1:, 2;2:, 1, 3;3:",
+		},
+		{
+			format: "%n %m\n%M\r\n",
+			opts:   []graph.PrinterOption{graph.CRLF()},
+			graph: graph.Graph{
+				Nodes: []graph.Node{{ID: 1}, {ID: 2}, {ID: 3}},
+				Edges: []graph.Edge{{Src: 1, Dst: 2}, {Src: 2, Dst: 3}},
+			},
+			output: "3 2\r\n1 2\r\n2 3\r\n",
+		},
+		{
+			format: "%P2w|%Dw|%FDw|%10P1w|%.1XP3M|%CDW",
+			graph: graph.Graph{
+				Nodes: []graph.Node{{ID: 1, Cost: 12.5}, {ID: 2, Cost: 0.1 * 3}, {ID: 3, Cost: -0.2}},
+				Edges: []graph.Edge{{Src: 1, Dst: 2, Cost: 30}},
+			},
+			output: "12.50 0.30 -0.20|13 0 0|12 0 -1|125.0 3.0 -2.0|1 2 3.000|0 30 0\n30 0 0\n0 0 0",
+		},
+		{
+			format: "%[sort=id]N|%[sort=label]#N|%[sort=cost-desc]1N|%[sort=id,sep=-,row=\" \"]M|%[sort=label,row=\" \"]#M|%[sort=cost,row=\" \"]1M",
+			opts:   []graph.PrinterOption{graph.NormalizeUndirected()},
+			graph: graph.Graph{
+				Nodes: []graph.Node{{ID: 3, Label: "A", Cost: 1}, {ID: 1, Label: "C", Cost: 3}, {ID: 2, Label: "B", Cost: 2}},
+				Edges: []graph.Edge{
+					{Src: 3, Dst: 1, Cost: 2},
+					{Src: 3, Dst: 2, Cost: 1, Directed: true},
+					{Src: 2, Dst: 1, Cost: 2},
+				},
+			},
+			output: "1\n2\n3|A\nB\nC|1 3\n2 2\n3 1|1-2 1-3 3-2|A B C A C B|3 2 1 1 3 2 1 2 2",
+		},
+		{format: "%[sort=asc]M", hasErr: true},
+		{format: "%[sort=id]L", hasErr: true},
+		{
+			format: "%G\n%2G",
+			graph: graph.Graph{
+				Nodes: []graph.Node{
+					{ID: 1, Label: `Say "hi"`, Graphics: graph.Graphics{Center: graph.Point{X: 10, Y: 20}}},
+					{ID: 5, Cost: 1.5},
+				},
+				Edges: []graph.Edge{{Src: 1, Dst: 5, Cost: 2, Label: "E"}},
+			},
+			output: "graph {\n  1 [label=\"Say \\\"hi\\\"\", pos=\"10,-20!\"];\n  5 [xlabel=\"1.5\", cost=\"1.5\", pos=\"0,0!\"];\n  1 -- 5 [label=\"E (2)\", cost=\"2\"];\n}\n" +
+				"graph {\n  1 [label=\"Say \\\"hi\\\"\", pos=\"10,-20!\"];\n  5 [xlabel=\"3\", cost=\"3\", pos=\"0,0!\"];\n  1 -- 5 [label=\"E (4)\", cost=\"4\"];\n}",
+		},
+		{
+			format: "%G",
+			graph: graph.Graph{
+				Nodes: []graph.Node{{ID: 1}, {ID: 2}},
+				Edges: []graph.Edge{{Src: 1, Dst: 2}, {Src: 2, Dst: 1, Directed: true}},
+			},
+			output: "digraph {\n  1;\n  2;\n  1 -> 2 [dir=none];\n  2 -> 1;\n}",
+		},
+		{format: "%#G", hasErr: true},
+		{format: "%PM", hasErr: true},
+		{format: "%P99M", hasErr: true},
+		{format: "%DI", hasErr: true},
+		{format: "%[row=;]w", hasErr: true},
+		{format: "%[row=;]d", hasErr: true},
 	}
 
 	for _, test := range tests {
 		t.Run(test.format, func(t *testing.T) {
-			p, err := graph.ParsePrinter(test.format)
+			p, err := graph.ParsePrinter(test.format, test.opts...)
 			if test.hasErr != (err != nil) {
 				t.Fatalf("Error expected: %t, error received: %v", test.hasErr, err)
 			}
@@ -79,6 +280,30 @@ Adjacency matrix:
 	}
 }
 
+func TestToDOT(t *testing.T) {
+	var sb strings.Builder
+	if err := graph.ToDOT(&sb, &gappedGraph); err != nil {
+		t.Fatal(err)
+	}
+
+	// The node IDs must be kept as they are.
+	if !strings.Contains(sb.String(), "  7 -- 4;\n") || !strings.HasSuffix(sb.String(), "}\n") {
+		t.Fatalf("Invalid output: %q", sb.String())
+	}
+}
+
+func TestPresets(t *testing.T) {
+	for _, name := range graph.PresetNames() {
+		format, ok := graph.Preset(name)
+		if !ok {
+			t.Fatalf("Preset %q not found", name)
+		}
+		if _, err := graph.ParsePrinter(format); err != nil {
+			t.Fatalf("Preset %q is invalid: %v", name, err)
+		}
+	}
+}
+
 func BenchmarkPrinter(b *testing.B) {
 	f, _ := benchFiles.ReadFile("benchfile.xml")
 	var g graph.Graph
@@ -0,0 +1,38 @@
+package graph
+
+import (
+	"bufio"
+	"io"
+)
+
+// A Reader reads a graph written in some format.
+type Reader interface {
+	ReadGraph(r io.Reader) (Graph, error)
+}
+
+// ReaderFunc is a function which reads a graph, used as a Reader.
+type ReaderFunc func(r io.Reader) (Graph, error)
+
+func (f ReaderFunc) ReadGraph(r io.Reader) (Graph, error) {
+	return f(r)
+}
+
+var (
+	// XMLReader reads graph.jar XML files using FromXMLNoStd.
+	XMLReader Reader = ReaderFunc(func(r io.Reader) (Graph, error) {
+		br, ok := r.(*bufio.Reader)
+		if !ok {
+			br = bufio.NewReader(r)
+		}
+		return FromXMLNoStd(br)
+	})
+	// DOTReader reads Graphviz DOT files using FromDOT.
+	DOTReader Reader = ReaderFunc(FromDOT)
+	// GraphMLReader reads GraphML files using FromGraphML.
+	GraphMLReader Reader = ReaderFunc(FromGraphML)
+)
+
+// ReadGraph reads a graph using the scanner, so that a Scanner is a Reader.
+func (sc *Scanner) ReadGraph(r io.Reader) (Graph, error) {
+	return sc.Scan(r)
+}
@@ -0,0 +1,982 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ScanError is the type of error returned by Scanner.Scan
+// when the input doesn't match the format string.
+type ScanError struct {
+	// Any underlying error that may have occurred when scanning.
+	Reason error
+	// The 1-based line of the input where the error occurred.
+	Line int
+	// Additional details about the error.
+	Explanation string
+}
+
+func (s *ScanError) Error() string {
+	r := "line " + strconv.Itoa(s.Line) + ": " + s.Explanation
+	if s.Reason != nil {
+		r += ": " + s.Reason.Error()
+	}
+	return r
+}
+
+func (s *ScanError) Unwrap() error {
+	return s.Reason
+}
+
+// A Scanner reads a graph written by a Printer with the same format string,
+// so that files converted in the past can be converted again to other formats.
+// Use ParseScanner to create a Scanner.
+type Scanner struct {
+	ops []scanOperation
+	// follows holds, for each operation, the text which follows it in the format
+	// string, used to tell where the values read by the operation end.
+	follows []string
+	// texts holds, for each operation, the whole text which follows it,
+	// used to tell where the lists without a count end.
+	texts []string
+	p     *Printer
+	// idBase is the ID of the first node printed by the matrix verbs.
+	idBase        int
+	directedEdges bool
+}
+
+type scanOperation interface {
+	scan(*scanState) error
+}
+
+// A ScannerOption configures a Scanner created by ParseScanner. The PrinterOptions
+// are ScannerOptions too, as a Scanner must know how the input was printed.
+type ScannerOption interface {
+	scannerOption()
+}
+
+func (PrinterOption) scannerOption() {}
+
+// scannerOnlyOption is a ScannerOption which has no meaning for printers.
+type scannerOnlyOption func(*Scanner)
+
+func (scannerOnlyOption) scannerOption() {}
+
+// DirectedEdges makes a Scanner read the edges printed by the M verb as directed
+// edges, and the edges which appear in both directions in the adjacency list,
+// the adjacency matrix and the cost matrix as two directed edges.
+func DirectedEdges() ScannerOption {
+	return scannerOnlyOption(func(s *Scanner) {
+		s.directedEdges = true
+	})
+}
+
+// ParseScanner creates a scanner from the given format string and options,
+// which are the same as the ones given to ParsePrinter to write the input.
+//
+// The whitespace in the format string matches any whitespace in the input,
+// as long as both contain a line break or neither does. Everything else must
+// match exactly. The verbs read the following:
+//   - n, m: the number of nodes and edges. When they are read before the verbs
+//     which print a value for each node or edge, they tell how many values there
+//     are; otherwise values are read as long as they match the format.
+//   - N, M, w: the nodes, edges and node costs. The cost functions are reversed
+//     by dividing the costs by their ratio, the rounding being lost.
+//   - L, a, W: the edges of the graph. Edges which appear in both directions,
+//     with the same cost, are read as a single undirected edge.
+//   - I: the edges of the graph, including their direction.
+//   - d, i, o: the degrees are read, but not used.
+//
+// Nodes printed by the matrix verbs are given IDs starting from the ID base,
+// or from 1 if the IDBase option isn't given.
+// If the format prints the edges more than once, the first verb is used.
+// Labels printed with the "#" flag must not contain the separators.
+func ParseScanner(format string, opts ...ScannerOption) (*Scanner, error) {
+	var printerOpts []PrinterOption
+	var scannerOpts []scannerOnlyOption
+	for _, opt := range opts {
+		switch opt := opt.(type) {
+		case PrinterOption:
+			printerOpts = append(printerOpts, opt)
+		case scannerOnlyOption:
+			scannerOpts = append(scannerOpts, opt)
+		}
+	}
+
+	p, err := ParsePrinter(format, printerOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Scanner{p: p, idBase: 1}
+	for _, opt := range scannerOpts {
+		opt(s)
+	}
+	if p.compactIDs {
+		s.idBase = p.idBase
+	}
+	for i, op := range p.ops {
+		sop, ok := op.(scanOperation)
+		if !ok {
+			return nil, &ParsePrinterError{
+				Format:      format,
+				Explanation: "format can't be scanned",
+			}
+		}
+
+		var follow, text string
+		if i+1 < len(p.ops) {
+			if t, ok := p.ops[i+1].(textOperation); ok {
+				text = string(t)
+				if fields := strings.Fields(text); len(fields) > 0 {
+					follow = fields[0]
+				}
+			}
+		}
+
+		s.ops = append(s.ops, sop)
+		s.follows = append(s.follows, follow)
+		s.texts = append(s.texts, text)
+	}
+
+	return s, nil
+}
+
+// MustParseScanner is the same as ParseScanner, but panics on non-nil error.
+func MustParseScanner(format string, opts ...ScannerOption) *Scanner {
+	s, err := ParseScanner(format, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// Scan reads a graph from the given reader. The whole input must match
+// the format string, except for trailing whitespace.
+func (sc *Scanner) Scan(r io.Reader) (Graph, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Graph{}, err
+	}
+
+	s := &scanState{
+		in:        string(data),
+		p:         sc.p,
+		nodeCount: -1,
+		edgeCount: -1,
+		idBase:    sc.idBase,
+		directed:  sc.directedEdges,
+		maxID:     sc.idBase - 1,
+		labels:    map[string]int{},
+	}
+
+	for i, op := range sc.ops {
+		s.follow, s.text = sc.follows[i], sc.texts[i]
+		if err := op.scan(s); err != nil {
+			return Graph{}, err
+		}
+	}
+
+	s.skipSpace(true)
+	if s.pos != len(s.in) {
+		return Graph{}, s.errorf("unexpected text %q", s.peek())
+	}
+
+	return s.graph(), nil
+}
+
+// scanState holds the input and what was read from it by the operations
+// of a single Scan call.
+type scanState struct {
+	in     string
+	pos    int
+	p      *Printer
+	follow string
+	text   string
+	idBase int
+	// directed is true if the edges are read as directed edges.
+	directed bool
+
+	// nodeCount and edgeCount are -1 until they are read.
+	nodeCount int
+	edgeCount int
+	// implied is the number of nodes implied by the matrices and lists.
+	implied int
+	maxID   int
+
+	nodes     []Node
+	hasNodes  bool
+	nodeCosts bool
+	costs     []float64
+	hasCosts  bool
+	edges     []Edge
+	hasEdges  bool
+	// labels maps the labels read to node IDs, and extra holds the nodes
+	// first found by their label outside of the N verb.
+	labels map[string]int
+	extra  []Node
+}
+
+func (s *scanState) errorf(format string, args ...interface{}) error {
+	return &ScanError{
+		Line:        strings.Count(s.in[:s.pos], "\n") + 1,
+		Explanation: fmt.Sprintf(format, args...),
+	}
+}
+
+// peek returns the rest of the current line, for error messages.
+func (s *scanState) peek() string {
+	rest := s.in[s.pos:]
+	if i := strings.IndexAny(rest, "\r\n"); i != -1 {
+		rest = rest[:i]
+	}
+	if len(rest) > 20 {
+		rest = rest[:20] + "..."
+	}
+	return rest
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// skipSpace skips the whitespace at the current position. Line breaks
+// are skipped only if lines is true. It returns the number of line breaks.
+func (s *scanState) skipSpace(lines bool) int {
+	var breaks int
+	for s.pos < len(s.in) && isSpace(s.in[s.pos]) {
+		if s.in[s.pos] == '\n' {
+			if !lines {
+				break
+			}
+			breaks++
+		}
+		s.pos++
+	}
+	return breaks
+}
+
+// literal reads the given text. A run of whitespace in the text matches any
+// run of whitespace in the input, which must contain a line break if and only
+// if the text's run does. If the text doesn't match, nothing is read.
+func (s *scanState) literal(text string) bool {
+	start := s.pos
+
+	for i := 0; i < len(text); {
+		if !isSpace(text[i]) {
+			if s.pos == len(s.in) || s.in[s.pos] != text[i] {
+				s.pos = start
+				return false
+			}
+			i++
+			s.pos++
+			continue
+		}
+
+		lines := false
+		for ; i < len(text) && isSpace(text[i]); i++ {
+			lines = lines || text[i] == '\n'
+		}
+		// A line break matches the end of the input, as the last line
+		// of a file may not end with one.
+		if breaks := s.skipSpace(lines); lines && breaks == 0 && s.pos != len(s.in) {
+			s.pos = start
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *scanState) expect(text string) error {
+	if !s.literal(text) {
+		return s.errorf("expected %q, found %q", text, s.peek())
+	}
+	return nil
+}
+
+// stops returns the texts which end the values read by an operation
+// with the given separators.
+func (s *scanState) stops(sep separators, extra ...string) []string {
+	stops := extra
+	for _, v := range []string{sep.field, sep.row} {
+		if t := strings.TrimSpace(v); t != "" {
+			v = t
+		}
+		if v != "" {
+			stops = append(stops, v)
+		}
+	}
+	if s.follow != "" {
+		stops = append(stops, s.follow)
+	}
+	return stops
+}
+
+// token reads a value which ends at a line break, at one of the given texts
+// or, if spaces is true, at whitespace.
+func (s *scanState) token(stops []string, spaces bool) string {
+	if spaces {
+		for s.pos < len(s.in) && (s.in[s.pos] == ' ' || s.in[s.pos] == '\t') {
+			s.pos++
+		}
+	}
+
+	start := s.pos
+loop:
+	for ; s.pos < len(s.in); s.pos++ {
+		c := s.in[s.pos]
+		if c == '\n' || c == '\r' || spaces && isSpace(c) {
+			break
+		}
+		for _, stop := range stops {
+			if stop != "" && strings.HasPrefix(s.in[s.pos:], stop) {
+				break loop
+			}
+		}
+	}
+
+	return s.in[start:s.pos]
+}
+
+func (s *scanState) int(stops []string) (int, error) {
+	start := s.pos
+	t := s.token(stops, true)
+	v, err := strconv.Atoi(t)
+	if err != nil {
+		s.pos = start
+		return 0, s.errorf("expected an integer, found %q", s.peek())
+	}
+	return v, nil
+}
+
+// cost reads a cost printed with the given cost function.
+func (s *scanState) cost(stops []string, fn *costFunction) (float64, error) {
+	start := s.pos
+	t := s.token(stops, true)
+	v, err := strconv.ParseFloat(t, 64)
+	if err != nil {
+		s.pos = start
+		return 0, s.errorf("expected a cost, found %q", s.peek())
+	}
+	if fn != nil && fn.ratio != 0 {
+		v /= fn.ratio
+	}
+	return v, nil
+}
+
+// node reads the ID or, if labels is true, the label of a node
+// and returns the node's ID.
+func (s *scanState) node(stops []string, labels bool) (int, error) {
+	if !labels {
+		id, err := s.int(stops)
+		if err == nil {
+			s.see(id)
+		}
+		return id, err
+	}
+
+	start := s.pos
+	label := s.token(stops, false)
+	if label == "" {
+		s.pos = start
+		return 0, s.errorf("expected a label, found %q", s.peek())
+	}
+
+	if id, ok := s.labels[label]; ok {
+		return id, nil
+	}
+	if !s.p.hasLabelFallback {
+		if id, err := strconv.Atoi(label); err == nil {
+			// Nodes without labels are printed using their ID.
+			s.see(id)
+			return id, nil
+		}
+	}
+
+	// A node which wasn't printed by the N verb.
+	s.maxID++
+	s.labels[label] = s.maxID
+	s.extra = append(s.extra, Node{ID: s.maxID, Label: label})
+	return s.maxID, nil
+}
+
+// see records a node ID which was read.
+func (s *scanState) see(id int) {
+	if id > s.maxID {
+		s.maxID = id
+	}
+}
+
+func (s *scanState) imply(nodes int) {
+	if nodes > s.implied {
+		s.implied = nodes
+	}
+	s.see(s.idBase + nodes - 1)
+}
+
+// ends reports whether the input at the current position is followed by sep,
+// by the text which follows the operation or by the end of the input.
+// Nothing is read.
+func (s *scanState) ends(sep string) bool {
+	start := s.pos
+	defer func() { s.pos = start }()
+
+	if s.literal(sep) || s.text != "" && s.literal(s.text) {
+		return true
+	}
+	s.skipSpace(true)
+	return s.pos == len(s.in)
+}
+
+// list reads count items separated by sep and returns the number of items read.
+// If count is negative, items are read as long as they match and are followed by
+// sep, by the text which follows the operation or by the end of the input, so that
+// the values of the next operation aren't read. An item must not change the state
+// unless it succeeds, except for collecting its value: the values collected after
+// the returned number of items must be dropped.
+func (s *scanState) list(count int, sep string, item func(i int) error) (int, error) {
+	for i := 0; count < 0 || i < count; i++ {
+		start := s.pos
+
+		var err error
+		if i > 0 {
+			err = s.expect(sep)
+		}
+		if err == nil {
+			err = item(i)
+		}
+		if err == nil && count < 0 && !s.ends(sep) {
+			err = s.errorf("unexpected text %q", s.peek())
+		}
+
+		if err != nil {
+			if count < 0 {
+				s.pos = start
+				return i, nil
+			}
+			return i, err
+		}
+	}
+
+	return count, nil
+}
+
+// cells reads a row of count raw values separated by sep.
+// If count is negative, values are read as long as there are any.
+func (s *scanState) cells(count int, sep separators) ([]string, error) {
+	var cells []string
+	stops := s.stops(sep)
+
+	n, err := s.list(count, sep.field, func(int) error {
+		start := s.pos
+		cell := s.token(stops, true)
+		if cell == "" {
+			s.pos = start
+			return s.errorf("expected a value, found %q", s.peek())
+		}
+		cells = append(cells, cell)
+		return nil
+	})
+
+	return cells[:n], err
+}
+
+// matrix reads a square matrix with a row and a column for each node.
+func (s *scanState) matrix(sep separators) ([][]string, error) {
+	n := s.nodeCount
+	var rows [][]string
+
+	if n < 0 {
+		first, err := s.cells(-1, sep)
+		if err != nil {
+			return nil, err
+		}
+		if n = len(first); n == 0 {
+			return nil, nil
+		}
+		rows = append(rows, first)
+	}
+
+	for i := len(rows); i < n; i++ {
+		if i > 0 {
+			if err := s.expect(sep.row); err != nil {
+				return nil, err
+			}
+		}
+
+		row, err := s.cells(n, sep)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+
+	s.imply(n)
+	return rows, nil
+}
+
+// arc is an edge read from a matrix or a list, whose direction is found
+// by looking for the same edge in the other direction.
+type arc struct {
+	src, dst int
+	cost     float64
+}
+
+// pair creates the edges from the given arcs. An arc matched by a later arc in
+// the other direction with the same cost is read as an undirected edge, unless
+// the scanner reads all edges as directed. Loops are undirected.
+func (s *scanState) pair(arcs []arc) []Edge {
+	edges := make([]Edge, 0, len(arcs))
+	pending := map[arc][]int{}
+
+	for _, a := range arcs {
+		if s.directed {
+			edges = append(edges, Edge{Src: a.src, Dst: a.dst, Cost: a.cost, Directed: true})
+			continue
+		}
+		if a.src == a.dst {
+			edges = append(edges, Edge{Src: a.src, Dst: a.dst, Cost: a.cost})
+			continue
+		}
+
+		mirror := arc{src: a.dst, dst: a.src, cost: a.cost}
+		if p := pending[mirror]; len(p) > 0 {
+			edges[p[0]].Directed = false
+			pending[mirror] = p[1:]
+			continue
+		}
+
+		pending[a] = append(pending[a], len(edges))
+		edges = append(edges, Edge{Src: a.src, Dst: a.dst, Cost: a.cost, Directed: true})
+	}
+
+	return edges
+}
+
+func (s *scanState) setEdges(edges []Edge) {
+	if !s.hasEdges {
+		s.edges = edges
+		s.hasEdges = true
+	}
+}
+
+// graph builds the graph from what was read.
+func (s *scanState) graph() Graph {
+	g := Graph{Nodes: s.nodes, Edges: s.edges}
+
+	if !s.hasNodes {
+		n := s.nodeCount
+		if n < 0 {
+			n = s.implied
+		}
+		if n < len(s.costs) {
+			n = len(s.costs)
+		}
+
+		if n > 0 || len(s.edges) == 0 {
+			for i := 0; i < n; i++ {
+				g.Nodes = append(g.Nodes, Node{ID: s.idBase + i})
+			}
+		} else {
+			// Only the edges were read, so the nodes are their endpoints.
+			seen := map[int]bool{}
+			for _, e := range s.edges {
+				seen[e.Src] = true
+				seen[e.Dst] = true
+			}
+			for _, n := range s.extra {
+				delete(seen, n.ID)
+			}
+			ids := make([]int, 0, len(seen))
+			for id := range seen {
+				ids = append(ids, id)
+			}
+			sort.Ints(ids)
+			for _, id := range ids {
+				g.Nodes = append(g.Nodes, Node{ID: id})
+			}
+		}
+	}
+
+	if !s.nodeCosts {
+		for i, c := range s.costs {
+			if i < len(g.Nodes) {
+				g.Nodes[i].Cost = c
+			}
+		}
+	}
+
+	g.Nodes = append(g.Nodes, s.extra...)
+	return g
+}
+
+func (t textOperation) scan(s *scanState) error {
+	return s.expect(string(t))
+}
+
+func (c countOperation) scan(s *scanState) error {
+	v, err := s.int([]string{s.follow})
+	if err != nil {
+		return err
+	}
+	if v < 0 {
+		return s.errorf("invalid count %d", v)
+	}
+
+	count := &s.nodeCount
+	if c == verbEdgesCount {
+		count = &s.edgeCount
+	}
+	if *count >= 0 && *count != v {
+		return s.errorf("count %d doesn't match the count %d read before", v, *count)
+	}
+	*count = v
+
+	return nil
+}
+
+func (c *costsOperation) scan(s *scanState) error {
+	var costs []float64
+	stops := s.stops(c.sep)
+
+	n, err := s.list(s.nodeCount, c.sep.field, func(int) error {
+		v, err := s.cost(stops, &c.cost)
+		if err == nil {
+			costs = append(costs, v)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if !s.hasCosts {
+		s.costs = costs[:n]
+		s.hasCosts = true
+	}
+	return nil
+}
+
+func (v *verticesOperation) scan(s *scanState) error {
+	var nodes []Node
+	stops := s.stops(v.sep)
+
+	n, err := s.list(s.nodeCount, v.sep.row, func(i int) error {
+		start := s.pos
+		nd := Node{ID: s.idBase + i}
+		var err error
+
+		readCost := func() error {
+			if v.cost != nil {
+				nd.Cost, err = s.cost(stops, v.cost)
+			}
+			return err
+		}
+
+		if v.prefixCost && v.cost != nil {
+			if err = readCost(); err == nil {
+				err = s.expect(v.sep.field)
+			}
+		}
+		if err == nil {
+			if v.labels {
+				nd.Label = s.token(stops, false)
+				if nd.Label == "" {
+					err = s.errorf("expected a label, found %q", s.peek())
+				}
+			} else {
+				nd.ID, err = s.int(stops)
+			}
+		}
+		if err == nil && !v.prefixCost && v.cost != nil {
+			if err = s.expect(v.sep.field); err == nil {
+				err = readCost()
+			}
+		}
+
+		if err != nil {
+			s.pos = start
+			return err
+		}
+
+		nodes = append(nodes, nd)
+		return nil
+	})
+	if err != nil || s.hasNodes {
+		return err
+	}
+
+	nodes = nodes[:n]
+	for i := range nodes {
+		nd := &nodes[i]
+		if v.labels {
+			if s.p.hasLabelFallback && nd.Label == s.p.labelFallback || !s.p.hasLabelFallback && nd.Label == strconv.Itoa(nd.ID) {
+				// The node has no label.
+				nd.Label = ""
+			} else {
+				s.labels[nd.Label] = nd.ID
+			}
+		}
+		s.see(nd.ID)
+	}
+
+	s.nodes = nodes
+	s.hasNodes = true
+	s.nodeCosts = v.cost != nil
+	return nil
+}
+
+func (v *edgesOperation) scan(s *scanState) error {
+	var edges []Edge
+	stops := s.stops(v.sep)
+
+	n, err := s.list(s.edgeCount, v.sep.row, func(int) error {
+		start := s.pos
+		e := Edge{Directed: directed(s.directed)}
+		var err error
+
+		readCost := func() error {
+			if v.cost != nil {
+				e.Cost, err = s.cost(stops, v.cost)
+			}
+			return err
+		}
+
+		if v.prefixCost && v.cost != nil {
+			if err = readCost(); err == nil {
+				err = s.expect(v.sep.field)
+			}
+		}
+		if err == nil {
+			e.Src, err = s.node(stops, v.labels)
+		}
+		if err == nil {
+			err = s.expect(v.sep.field)
+		}
+		if err == nil {
+			e.Dst, err = s.node(stops, v.labels)
+		}
+		if err == nil && !v.prefixCost && v.cost != nil {
+			if err = s.expect(v.sep.field); err == nil {
+				err = readCost()
+			}
+		}
+
+		if err != nil {
+			s.pos = start
+			return err
+		}
+
+		edges = append(edges, e)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.setEdges(edges[:n])
+	return nil
+}
+
+func (a *adjacencyListOperation) scan(s *scanState) error {
+	var lists [][]arc
+	stops := s.stops(a.sep, ":")
+
+	rows, err := s.list(s.nodeCount, a.sep.row, func(i int) error {
+		start := s.pos
+		src := s.idBase + i
+		degree := -1
+		var row []arc
+		var err error
+
+		if a.degree {
+			if degree, err = s.int(stops); err == nil && degree < 0 {
+				err = s.errorf("invalid degree %d", degree)
+			}
+		} else if src, err = s.node(stops, a.labels); err == nil {
+			err = s.expect(":")
+		}
+
+		if err == nil {
+			_, err = s.list(degree, "", func(int) error {
+				nb := arc{src: src}
+				err := s.expect(a.sep.field)
+				if err == nil {
+					nb.dst, err = s.node(stops, a.labels)
+				}
+				if err == nil && a.cost != nil {
+					if err = s.expect(a.sep.field); err == nil {
+						nb.cost, err = s.cost(stops, a.cost)
+					}
+				}
+				if err == nil {
+					row = append(row, nb)
+				}
+				return err
+			})
+		}
+
+		if err != nil {
+			s.pos = start
+			return err
+		}
+
+		lists = append(lists, row)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var arcs []arc
+	for _, row := range lists[:rows] {
+		arcs = append(arcs, row...)
+	}
+
+	s.imply(rows)
+	s.setEdges(s.pair(arcs))
+	return nil
+}
+
+func (a *adjacencyMatrixOperation) scan(s *scanState) error {
+	start := s.pos
+	rows, err := s.matrix(a.sep)
+	if err != nil {
+		return err
+	}
+
+	var arcs []arc
+	for i, row := range rows {
+		for j, cell := range row {
+			switch cell {
+			case "0":
+			case "1":
+				arcs = append(arcs, arc{src: s.idBase + i, dst: s.idBase + j})
+			default:
+				s.pos = start
+				return s.errorf("invalid adjacency matrix value %q", cell)
+			}
+		}
+	}
+
+	s.setEdges(s.pair(arcs))
+	return nil
+}
+
+func (c *costMatrixOperation) scan(s *scanState) error {
+	start := s.pos
+	rows, err := s.matrix(c.sep)
+	if err != nil {
+		return err
+	}
+
+	var arcs []arc
+	for i, row := range rows {
+		for j, cell := range row {
+			if cell == c.none && !(i == j && c.hasDiagonal) || i == j && c.hasDiagonal && cell == c.diagonal {
+				continue
+			}
+
+			v, err := strconv.ParseFloat(cell, 64)
+			if err != nil {
+				s.pos = start
+				return s.errorf("invalid cost matrix value %q", cell)
+			}
+			if c.cost.ratio != 0 {
+				v /= c.cost.ratio
+			}
+
+			arcs = append(arcs, arc{src: s.idBase + i, dst: s.idBase + j, cost: v})
+		}
+	}
+
+	s.setEdges(s.pair(arcs))
+	return nil
+}
+
+func (o *incidenceMatrixOperation) scan(s *scanState) error {
+	start := s.pos
+	columns := s.edgeCount
+	var rows [][]string
+
+	n, err := s.list(s.nodeCount, o.sep.row, func(int) error {
+		row, err := s.cells(columns, o.sep)
+		if err == nil && len(row) == 0 {
+			err = s.errorf("expected a value, found %q", s.peek())
+		}
+		if err != nil {
+			return err
+		}
+		if columns < 0 {
+			columns = len(row)
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	rows = rows[:n]
+	if columns < 0 {
+		columns = 0
+	}
+
+	edges := make([]Edge, 0, columns)
+	for j := 0; j < columns; j++ {
+		var ones, outs, ins []int
+		for i, row := range rows {
+			switch cell := row[j]; {
+			case cell == "1":
+				ones = append(ones, s.idBase+i)
+			case cell == o.out:
+				outs = append(outs, s.idBase+i)
+			case cell == o.in:
+				ins = append(ins, s.idBase+i)
+			case cell != "0":
+				s.pos = start
+				return s.errorf("invalid incidence matrix value %q", cell)
+			}
+		}
+
+		if len(outs) == 0 && len(ins) == 1 && len(ones) == 1 {
+			// The source is marked with 1 when it is the same as the mark
+			// of undirected edges.
+			outs, ones = ones, nil
+		}
+
+		switch {
+		case len(outs) == 1 && len(ins) <= 1 && len(ones) == 0:
+			e := Edge{Src: outs[0], Dst: outs[0], Directed: true}
+			if len(ins) == 1 {
+				e.Dst = ins[0]
+			}
+			edges = append(edges, e)
+		case len(ones) == 2 && len(outs) == 0 && len(ins) == 0:
+			edges = append(edges, Edge{Src: ones[0], Dst: ones[1]})
+		case len(ones) == 1 && len(outs) == 0 && len(ins) == 0:
+			edges = append(edges, Edge{Src: ones[0], Dst: ones[0], Directed: directed(s.directed)})
+		default:
+			s.pos = start
+			return s.errorf("invalid incidence matrix column %d", j+1)
+		}
+	}
+
+	s.imply(len(rows))
+	s.setEdges(edges)
+	return nil
+}
+
+func (d *degreesOperation) scan(s *scanState) error {
+	stops := s.stops(d.sep)
+
+	_, err := s.list(s.nodeCount, d.sep.field, func(int) error {
+		_, err := s.int(stops)
+		return err
+	})
+	return err
+}
@@ -0,0 +1,126 @@
+package graph_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tmaxmax/xml-to-graph/internal/graph"
+)
+
+var scannedGraph = graph.Graph{
+	Nodes: []graph.Node{{ID: 1, Cost: 2.5, Label: "Arad"}, {ID: 2, Cost: 1}, {ID: 3, Cost: 4, Label: "Cluj"}},
+	Edges: []graph.Edge{
+		{Src: 1, Dst: 2, Cost: 3},
+		{Src: 2, Dst: 3, Cost: 1.5},
+		{Src: 3, Dst: 3, Cost: 2},
+	},
+}
+
+var scannedDigraph = graph.Graph{
+	Nodes: []graph.Node{{ID: 1}, {ID: 2}, {ID: 3}},
+	Edges: []graph.Edge{
+		{Src: 1, Dst: 2, Directed: true},
+		{Src: 2, Dst: 3},
+		{Src: 3, Dst: 1, Directed: true},
+	},
+}
+
+func TestScanner(t *testing.T) {
+	tests := []struct {
+		format string
+		opts   []graph.PrinterOption
+		// scanOpts are given only to the scanner.
+		scanOpts []graph.ScannerOption
+		graph    graph.Graph
+		// scanned is the graph read back, if it is not the same as the one printed.
+		scanned *graph.Graph
+	}{
+		{format: "%n %m\n%1N\n%1M\n", graph: scannedGraph, scanned: &graph.Graph{
+			Nodes: []graph.Node{{ID: 1, Cost: 2.5}, {ID: 2, Cost: 1}, {ID: 3, Cost: 4}},
+			Edges: scannedGraph.Edges,
+		}},
+		{format: "%n\n%#N\n%#.5M", graph: graph.Graph{
+			Nodes: scannedGraph.Nodes[:2],
+			Edges: scannedGraph.Edges[:1],
+		}, scanned: &graph.Graph{
+			Nodes: []graph.Node{{ID: 1, Label: "Arad"}, {ID: 2}},
+			Edges: []graph.Edge{{Src: 1, Dst: 2, Cost: 3}},
+		}},
+		{format: "%[sep=\",\",row=\";\"]1M|%w", graph: scannedGraph, scanned: &graph.Graph{
+			Nodes: []graph.Node{{ID: 1, Cost: 2.5}, {ID: 2, Cost: 1}, {ID: 3, Cost: 4}},
+			Edges: scannedGraph.Edges,
+		}},
+		{format: "%n %m\n%M\n", scanOpts: []graph.ScannerOption{graph.DirectedEdges()}, graph: graph.Graph{
+			Nodes: scannedDigraph.Nodes,
+			Edges: []graph.Edge{{Src: 1, Dst: 2, Directed: true}, {Src: 3, Dst: 1, Directed: true}},
+		}},
+		{format: "%N\n%M\n", graph: scannedDigraph, scanned: &graph.Graph{
+			Nodes: scannedDigraph.Nodes,
+			Edges: []graph.Edge{{Src: 1, Dst: 2}, {Src: 2, Dst: 3}, {Src: 3, Dst: 1}},
+		}},
+		{format: "%N\n%M\n", graph: graph.Graph{Nodes: []graph.Node{{ID: 1}}}},
+		{format: "%n\n%a\n", graph: scannedDigraph},
+		{format: "%n\n%L\n", graph: scannedDigraph},
+		{format: "%n\n%+L\n", graph: scannedDigraph},
+		{format: "%a\n%w", graph: scannedDigraph},
+		{format: "%n\n%[none=INF,diag=0]W\n%w\n", graph: scannedGraph, scanned: &graph.Graph{
+			Nodes: []graph.Node{{ID: 1, Cost: 2.5}, {ID: 2, Cost: 1}, {ID: 3, Cost: 4}},
+			Edges: scannedGraph.Edges,
+		}},
+		{format: "%n %m\n%I\n%d\n", graph: scannedDigraph},
+		{format: "%I\n", graph: scannedDigraph},
+		{format: "%m\n%[out=2,in=3]I\n", graph: scannedDigraph},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			p := graph.MustParsePrinter(test.format, test.opts...)
+			scanOpts := test.scanOpts
+			for _, opt := range test.opts {
+				scanOpts = append(scanOpts, opt)
+			}
+			s := graph.MustParseScanner(test.format, scanOpts...)
+
+			sb := strings.Builder{}
+			_, _ = p.Print(&sb, &test.graph)
+
+			g, err := s.Scan(strings.NewReader(sb.String()))
+			if err != nil {
+				t.Fatalf("Failed to scan %q: %v", sb.String(), err)
+			}
+
+			expected := test.graph
+			if test.scanned != nil {
+				expected = *test.scanned
+			}
+			if !reflect.DeepEqual(g, expected) {
+				t.Fatalf("Invalid graph scanned from %q:\nexpected: %v\nreceived: %v", sb.String(), expected, g)
+			}
+		})
+	}
+}
+
+func TestScannerErrors(t *testing.T) {
+	tests := []struct {
+		format string
+		input  string
+	}{
+		{format: "%n %m\n%M\n", input: "3 2\n1 2\n"},
+		{format: "%n %m\n%M\n", input: "3 1\n1 2\n2 3\n"},
+		{format: "%n\n%a\n", input: "2\n0 1\n2 0\n"},
+		{format: "%n\n%n\n", input: "2\n3\n"},
+		{format: "Nodes: %n\n", input: "Edges: 2\n"},
+		{format: "%I\n", input: "1 1\n1 0\n1 0\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			_, err := graph.MustParseScanner(test.format).Scan(strings.NewReader(test.input))
+			if err == nil {
+				t.Fatalf("Expected error when scanning %q", test.input)
+			}
+			t.Log(err)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package graph
+
+import "math/rand"
+
+// Shuffle randomly reorders the edges of the graph and randomly swaps the
+// endpoints of its undirected edges. Directed edges keep their direction.
+// The same source of randomness always produces the same graph, so seed it
+// explicitly to get reproducible results.
+func Shuffle(g *Graph, r *rand.Rand) {
+	r.Shuffle(len(g.Edges), func(i, j int) {
+		g.Edges[i], g.Edges[j] = g.Edges[j], g.Edges[i]
+	})
+
+	for i := range g.Edges {
+		e := &g.Edges[i]
+		if !e.Directed && r.Intn(2) == 1 {
+			e.Src, e.Dst = e.Dst, e.Src
+		}
+	}
+}
+
+// PermuteIDs randomly reassigns the IDs of the graph's nodes among themselves
+// and updates the endpoints of the edges accordingly. The set of IDs is kept,
+// so the graph is only relabeled. Endpoints which don't belong to any node
+// are not changed.
+func PermuteIDs(g *Graph, r *rand.Rand) {
+	ids := make([]int, 0, len(g.Nodes))
+	seen := make(map[int]bool, len(g.Nodes))
+	for i := range g.Nodes {
+		if id := g.Nodes[i].ID; !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	perm := r.Perm(len(ids))
+	mapping := make(map[int]int, len(ids))
+	for i, id := range ids {
+		mapping[id] = ids[perm[i]]
+	}
+
+	for i := range g.Nodes {
+		g.Nodes[i].ID = mapping[g.Nodes[i].ID]
+	}
+	for i := range g.Edges {
+		e := &g.Edges[i]
+		if id, ok := mapping[e.Src]; ok {
+			e.Src = id
+		}
+		if id, ok := mapping[e.Dst]; ok {
+			e.Dst = id
+		}
+	}
+}
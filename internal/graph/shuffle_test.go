@@ -0,0 +1,77 @@
+package graph_test
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/tmaxmax/xml-to-graph/internal/graph"
+)
+
+func shuffleTestGraph() graph.Graph {
+	g := graph.Graph{}
+	for i := 1; i <= 10; i++ {
+		g.Nodes = append(g.Nodes, graph.Node{ID: i, Cost: float64(i)})
+	}
+	for i := 1; i < 10; i++ {
+		g.Edges = append(g.Edges,
+			graph.Edge{Src: i, Dst: i + 1, Cost: float64(i)},
+			graph.Edge{Src: i + 1, Dst: i, Cost: float64(-i), Directed: true},
+		)
+	}
+	return g
+}
+
+func TestShuffle(t *testing.T) {
+	a, b := shuffleTestGraph(), shuffleTestGraph()
+	graph.Shuffle(&a, rand.New(rand.NewSource(42)))
+	graph.Shuffle(&b, rand.New(rand.NewSource(42)))
+
+	if !reflect.DeepEqual(a, b) {
+		t.Fatalf("Shuffling with the same seed gave different graphs:\n%v\n%v", a, b)
+	}
+	if reflect.DeepEqual(a, shuffleTestGraph()) {
+		t.Fatalf("Graph was not shuffled")
+	}
+
+	// The costs identify the edges of the test graph.
+	sort.Slice(a.Edges, func(i, j int) bool { return a.Edges[i].Cost < a.Edges[j].Cost })
+	for _, e := range a.Edges {
+		i := int(e.Cost)
+		switch {
+		case bool(e.Directed) && (e.Src != -i+1 || e.Dst != -i):
+			t.Fatalf("Directed edge was changed: %v", e)
+		case !bool(e.Directed) && !(e.Src == i && e.Dst == i+1 || e.Src == i+1 && e.Dst == i):
+			t.Fatalf("Undirected edge was changed: %v", e)
+		}
+	}
+}
+
+func TestPermuteIDs(t *testing.T) {
+	g := shuffleTestGraph()
+	graph.PermuteIDs(&g, rand.New(rand.NewSource(42)))
+
+	// The node costs identify the original IDs.
+	mapping := map[int]int{}
+	for _, n := range g.Nodes {
+		mapping[int(n.Cost)] = n.ID
+	}
+	if len(mapping) != 10 {
+		t.Fatalf("Expected 10 distinct nodes, received %v", g.Nodes)
+	}
+
+	seen := map[int]bool{}
+	for _, id := range mapping {
+		if id < 1 || id > 10 || seen[id] {
+			t.Fatalf("IDs are not a permutation: %v", mapping)
+		}
+		seen[id] = true
+	}
+
+	for i, e := range shuffleTestGraph().Edges {
+		if g.Edges[i].Src != mapping[e.Src] || g.Edges[i].Dst != mapping[e.Dst] {
+			t.Fatalf("Edge %d was not relabeled: expected %d -> %d, received %v", i, mapping[e.Src], mapping[e.Dst], g.Edges[i])
+		}
+	}
+}
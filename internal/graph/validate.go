@@ -0,0 +1,143 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+)
+
+// Severity tells how serious an Issue is.
+type Severity int
+
+const (
+	// Warning is the severity of issues which don't prevent printing
+	// the graph correctly, but that may not be intended.
+	Warning Severity = iota
+	// Error is the severity of issues which make the printed graph invalid.
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// IssueKind identifies the kind of problem an Issue reports.
+type IssueKind int
+
+const (
+	// DanglingEndpoint is reported for edges whose source or target
+	// is not a node of the graph.
+	DanglingEndpoint IssueKind = iota
+	// DuplicateNode is reported for nodes whose ID is used by another node.
+	DuplicateNode
+	// InvalidCost is reported for nodes and edges whose cost is NaN or infinite.
+	InvalidCost
+	// ParallelEdge is reported for edges which connect the same nodes,
+	// in the same direction, as another edge.
+	ParallelEdge
+	// SelfLoop is reported for edges whose source is the same as the target.
+	SelfLoop
+	// MixedEdges is reported once for graphs which have both directed
+	// and undirected edges.
+	MixedEdges
+)
+
+// An Issue is a problem found in a graph by Validate.
+type Issue struct {
+	Kind     IssueKind
+	Severity Severity
+	// Message describes the issue, referring to edges by their
+	// 1-based position in the graph.
+	Message string
+}
+
+func (i Issue) String() string {
+	return i.Severity.String() + ": " + i.Message
+}
+
+type edgeKey struct {
+	src, dst int
+	directed bool
+}
+
+// Validate checks the graph for problems such as edges which reference
+// missing nodes, duplicate node IDs or invalid costs. Issues with the
+// Error severity make the printed graph invalid, while those with the
+// Warning severity may only be unintended. The issues are returned in
+// the order the nodes and edges appear in the graph.
+func Validate(g *Graph) []Issue {
+	var issues []Issue
+	add := func(kind IssueKind, sev Severity, format string, args ...interface{}) {
+		issues = append(issues, Issue{Kind: kind, Severity: sev, Message: fmt.Sprintf(format, args...)})
+	}
+
+	nodes := make(map[int]struct{}, len(g.Nodes))
+	for i := range g.Nodes {
+		n := &g.Nodes[i]
+		if _, ok := nodes[n.ID]; ok {
+			add(DuplicateNode, Error, "node %d is defined more than once", n.ID)
+		}
+		nodes[n.ID] = struct{}{}
+
+		if !isFinite(n.Cost) {
+			add(InvalidCost, Error, "node %d has invalid cost %v", n.ID, n.Cost)
+		}
+	}
+
+	edges := make(map[edgeKey]int, len(g.Edges))
+	var hasDirected, hasUndirected bool
+
+	for i := range g.Edges {
+		e := &g.Edges[i]
+		desc := describeEdge(i, e)
+
+		if _, ok := nodes[e.Src]; !ok {
+			add(DanglingEndpoint, Error, "%s: source node %d does not exist", desc, e.Src)
+		}
+		if _, ok := nodes[e.Dst]; !ok {
+			add(DanglingEndpoint, Error, "%s: target node %d does not exist", desc, e.Dst)
+		}
+		if !isFinite(e.Cost) {
+			add(InvalidCost, Error, "%s: invalid cost %v", desc, e.Cost)
+		}
+		if e.Src == e.Dst {
+			add(SelfLoop, Warning, "%s: self-loop", desc)
+		}
+
+		key := edgeKey{src: e.Src, dst: e.Dst, directed: bool(e.Directed)}
+		if !key.directed && key.src > key.dst {
+			key.src, key.dst = key.dst, key.src
+		}
+		if j, ok := edges[key]; ok {
+			add(ParallelEdge, Warning, "%s: parallel to edge %d", desc, j+1)
+		} else {
+			edges[key] = i
+		}
+
+		if e.Directed {
+			hasDirected = true
+		} else {
+			hasUndirected = true
+		}
+	}
+
+	if hasDirected && hasUndirected {
+		add(MixedEdges, Warning, "graph has both directed and undirected edges")
+	}
+
+	return issues
+}
+
+func describeEdge(i int, e *Edge) string {
+	arrow := "--"
+	if e.Directed {
+		arrow = "->"
+	}
+	return fmt.Sprintf("edge %d (%d %s %d)", i+1, e.Src, arrow, e.Dst)
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
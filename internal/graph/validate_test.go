@@ -0,0 +1,58 @@
+package graph_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/tmaxmax/xml-to-graph/internal/graph"
+)
+
+func TestValidate(t *testing.T) {
+	if issues := graph.Validate(&expected); len(issues) != 1 || issues[0].Kind != graph.MixedEdges {
+		t.Fatalf("Expected only a mixed edges issue, received %v", issues)
+	}
+
+	g := graph.Graph{
+		Nodes: []graph.Node{
+			{ID: 1},
+			{ID: 2, Cost: math.NaN()},
+			{ID: 1},
+		},
+		Edges: []graph.Edge{
+			{Src: 1, Dst: 2},
+			{Src: 2, Dst: 1},
+			{Src: 2, Dst: 2, Cost: math.Inf(1)},
+			{Src: 3, Dst: 1},
+		},
+	}
+
+	kinds := []graph.IssueKind{
+		graph.InvalidCost,
+		graph.DuplicateNode,
+		graph.ParallelEdge,
+		graph.InvalidCost,
+		graph.SelfLoop,
+		graph.DanglingEndpoint,
+	}
+	severities := []graph.Severity{
+		graph.Error,
+		graph.Error,
+		graph.Warning,
+		graph.Error,
+		graph.Warning,
+		graph.Error,
+	}
+
+	issues := graph.Validate(&g)
+	receivedKinds := make([]graph.IssueKind, len(issues))
+	receivedSeverities := make([]graph.Severity, len(issues))
+	for i, issue := range issues {
+		receivedKinds[i] = issue.Kind
+		receivedSeverities[i] = issue.Severity
+	}
+
+	if !reflect.DeepEqual(kinds, receivedKinds) || !reflect.DeepEqual(severities, receivedSeverities) {
+		t.Fatalf("Invalid issues:\nexpected kinds %v, severities %v\nreceived %v", kinds, severities, issues)
+	}
+}